@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -31,6 +32,7 @@ func main() {
 	defer stop()
 
 	cfg := config.Load()
+	resources.InitValidations()
 
 	db, err := database.NewPostgres(ctx, database.Config{
 		DSN:             cfg.DBDSN,
@@ -57,8 +59,50 @@ func main() {
 	}
 	defer redisClient.Close()
 
-	fileEngine := engine.NewFileEngine("./storage")
-	ng := gateway.NewNginxGateway("./nginx/conf.d", cfg.NginxDomain, "./storage")
+	storage, err := newStorage(cfg)
+	if err != nil {
+		slog.Error("Could not initialize storage", "Error", err)
+		os.Exit(1)
+	}
+	fileEngine := engine.NewFileEngine(storage, engine.Limits{
+		MaxArchiveBytes: cfg.ExtractMaxArchiveBytes,
+		MaxTotalBytes:   cfg.ExtractMaxBytes,
+		MaxFileBytes:    cfg.ExtractMaxFileBytes,
+		MaxFiles:        cfg.ExtractMaxFiles,
+		MaxDepth:        cfg.ExtractMaxDepth,
+		MaxRatio:        cfg.ExtractMaxRatio,
+	})
+	routing := gateway.NewRouting(cfg.GatewayRouting, cfg.NginxDomain, cfg.GatewayScheme)
+	ng := gateway.NewNginxGateway(gateway.NginxConfig{
+		ConfigDir:  "./nginx/conf.d",
+		Domain:     cfg.NginxDomain,
+		StorageDir: "./storage",
+		Resolver:   cfg.NginxResolver,
+		Routing:    routing,
+		Limits: gateway.RateLimit{
+			RequestsPerSecond: cfg.NginxRateLimit,
+			Burst:             cfg.NginxRateBurst,
+			Connections:       cfg.NginxConnLimit,
+		},
+	})
+	if err := ng.WriteSharedConfig(); err != nil {
+		slog.Error("Could not write shared nginx config", "Error", err)
+		os.Exit(1)
+	}
+
+	// Remove staging directories left behind by extractions interrupted by a crash or restart
+	if removed, err := fileEngine.SweepStaging(cfg.StagingMaxAge, ng.StorageDir()); err != nil {
+		slog.Error("Could not sweep staging directories", "Error", err)
+	} else if removed > 0 {
+		slog.Info("Removed stale staging directories", "count", removed)
+	}
+
+	// Free shared file contents no deployment uses any more, e.g. after an interrupted expiry cleanup
+	if freed, err := fileEngine.SweepBlobs(ctx); err != nil {
+		slog.Error("Could not sweep unused file contents", "Error", err)
+	} else if freed > 0 {
+		slog.Info("Freed unused file contents", "count", freed)
+	}
 
 	mux := http.NewServeMux()
 
@@ -66,7 +110,7 @@ func main() {
 	resources.InitWorkers(ctx, db, redisClient, fileEngine, ng)
 
 	// Initialize HTTP routes (service emits directly to Redis)
-	resources.InitHttps(mux, db, redisClient, fileEngine)
+	resources.InitHttps(mux, db, redisClient, fileEngine, routing)
 
 	mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -87,6 +131,23 @@ func main() {
 
 	slog.Info("HTTP server started")
 
+	var staticSrv *http.Server
+	if cfg.StaticGatewayAddr != "" {
+		staticSrv = &http.Server{
+			Addr:    cfg.StaticGatewayAddr,
+			Handler: resources.InitStaticGateway(db, fileEngine, routing),
+		}
+
+		go func() {
+			if err := staticSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("Static gateway error", "err", err)
+				os.Exit(1)
+			}
+		}()
+
+		slog.Info("Static gateway started", "addr", cfg.StaticGatewayAddr)
+	}
+
 	<-ctx.Done()
 
 	slog.Info("Shutting down HTTP server")
@@ -100,5 +161,30 @@ func main() {
 		slog.Info("Server stopped")
 	}
 
+	if staticSrv != nil {
+		if err := staticSrv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Static gateway shutdown failed", "err", err)
+		}
+	}
+
 	slog.Info("Background workers shut down via context cancellation")
 }
+
+// newStorage selects the storage backend for deployment files.
+func newStorage(cfg *config.Config) (engine.Storage, error) {
+	switch cfg.StorageDriver {
+	case "local", "":
+		return engine.NewLocalStorage("./storage"), nil
+	case "s3":
+		return engine.NewS3Storage(engine.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
@@ -68,10 +68,11 @@ const docTemplate = `{
                 }
             }
         },
-        "/deployments/upload": {
+        "/deployments/delta": {
             "post": {
+                "description": "Lists every file of the deployment by SHA-256 digest. The response names the digests the server does not store yet; upload each with PUT /deployments/{id}/blobs/{sha256}, then finalize the deployment. Uploads receiving no content for 24 hours are abandoned and their deployment fails.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
@@ -79,46 +80,27 @@ const docTemplate = `{
                 "tags": [
                     "deployments"
                 ],
-                "summary": "Upload a deployment artifact",
+                "summary": "Start a delta upload",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Project ID",
-                        "name": "project_id",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Content-addressable hash",
-                        "name": "hash",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "URL path prefix for Traefik routing",
-                        "name": "entry_path",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Binary file (zip or tar.gz)",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "Project, hash and file manifest",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.CreateDeltaUpload"
+                        }
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/internal_resources_deployment.Deployment"
+                            "$ref": "#/definitions/internal_resources_deployment.DeltaUpload"
                         }
                     },
                     "400": {
-                        "description": "Invalid request",
+                        "description": "Invalid request body",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
@@ -134,37 +116,55 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
+                    },
+                    "501": {
+                        "description": "Storage does not support delta uploads",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/deployments/{id}": {
-            "get": {
+        "/deployments/proxy": {
+            "post": {
+                "description": "The deployment becomes ready once every upstream answers its health probe with a status below 500.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "deployments"
                 ],
-                "summary": "Get a deployment by ID",
+                "summary": "Create a proxy deployment",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Deployment ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Project, hash and upstreams",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.CreateProxyDeployment"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/internal_resources_deployment.Deployment"
                         }
                     },
-                    "404": {
-                        "description": "Deployment not found",
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
@@ -178,46 +178,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/projects": {
-            "get": {
+        "/deployments/resumable": {
+            "post": {
+                "description": "Declares the archive's size and SHA-256 checksum. Send the archive in order with PATCH /deployments/{id}/upload, resuming from the offset reported by GET /deployments/{id}/upload after an interruption, then finalize the upload. Uploads receiving nothing for 24 hours are abandoned.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "deployments"
                 ],
-                "summary": "List projects",
+                "summary": "Start a resumable upload",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number (default: 1)",
-                        "name": "pageNumber",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Page size (default: 10, max: 100)",
-                        "name": "pageSize",
-                        "in": "query"
+                        "description": "Project, hash, archive size and checksum",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.CreateResumableUpload"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/internal_resources_project.ProjectPaged"
+                            "$ref": "#/definitions/internal_resources_deployment.ResumableUpload"
+                        },
+                        "headers": {
+                            "Upload-Offset": {
+                                "type": "integer",
+                                "description": "Bytes received so far"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid parameters",
+                        "description": "Invalid request body",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
                     },
                     "422": {
-                        "description": "Validation failed",
+                        "description": "Validation failed or archive too large",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
@@ -229,38 +234,65 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/deployments/upload": {
             "post": {
+                "description": "Archives are limited to 10 MB in one request; larger ones, or uploads over unreliable networks, use POST /deployments/resumable.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "deployments"
                 ],
-                "summary": "Create a project",
+                "summary": "Upload a deployment artifact",
                 "parameters": [
                     {
-                        "description": "Project Details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_resources_project.CreateProject"
-                        }
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "project_id",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Content-addressable hash",
+                        "name": "hash",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entry file (e.g. /index.html) or directory ending with /; defaults to infario.json or /",
+                        "name": "entry_path",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Serving mode: spa, static or clean_urls (defaults to infario.json, then the project setting)",
+                        "name": "serving_mode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Archive (zip, tar, tar.gz, tar.bz2, tar.zst or tar.xz), detected from its content",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/internal_resources_project.Project"
+                            "$ref": "#/definitions/internal_resources_deployment.Deployment"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid request",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
@@ -280,19 +312,19 @@ const docTemplate = `{
                 }
             }
         },
-        "/projects/{id}": {
+        "/deployments/{id}": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "deployments"
                 ],
-                "summary": "Get a project by ID",
+                "summary": "Get a deployment by ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Project ID",
+                        "description": "Deployment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -302,11 +334,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_resources_project.Project"
+                            "$ref": "#/definitions/internal_resources_deployment.Deployment"
                         }
                     },
                     "404": {
-                        "description": "Project not found",
+                        "description": "Deployment not found",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
@@ -318,33 +350,51 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/deployments/{id}/blobs/{sha256}": {
+            "put": {
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "projects"
+                    "deployments"
                 ],
-                "summary": "Delete a project",
+                "summary": "Upload a content of a delta upload",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Project ID",
+                        "description": "Deployment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "SHA-256 digest of the content",
+                        "name": "sha256",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.DeltaUpload"
+                        }
                     },
                     "404": {
-                        "description": "Project not found",
+                        "description": "Upload session not found",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
                     },
                     "422": {
-                        "description": "Validation failed",
+                        "description": "Validation failed or content does not match its digest",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
@@ -356,51 +406,54 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/deployments/{id}/diff": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "projects"
+                    "deployments"
                 ],
-                "summary": "Update a project",
+                "summary": "Compare the files of two deployments",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Project ID",
+                        "description": "Deployment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated Project Details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_resources_project.UpdateProject"
-                        }
+                        "type": "string",
+                        "description": "ID of the deployment to compare against, e.g. the one in production",
+                        "name": "against",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include unified diffs of text files up to 64 KiB",
+                        "name": "patch",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_resources_project.Project"
+                            "$ref": "#/definitions/internal_resources_deployment.DeploymentDiff"
                         }
                     },
-                    "400": {
-                        "description": "Invalid request body",
+                    "404": {
+                        "description": "Deployment not found",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Project not found",
+                    "409": {
+                        "description": "Deployment has no recorded files",
                         "schema": {
                             "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
                         }
@@ -419,60 +472,1617 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse": {
-            "description": "Standard API error response",
-            "type": "object",
-            "properties": {
-                "errors": {
-                    "description": "Detailed field errors",
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
-                    }
-                },
-                "message": {
-                    "description": "Human readable summary",
-                    "type": "string"
-                },
-                "status": {
-                    "description": "HTTP Status Code",
-                    "type": "integer"
-                }
-            }
         },
-        "internal_resources_deployment.Deployment": {
-            "description": "Deployment entity representing a built artifact with content-addressable identifier",
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "entry_path": {
-                    "description": "URL path prefix for Traefik routing",
-                    "type": "string"
-                },
-                "expired_at": {
+        "/deployments/{id}/files": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deployments"
+                ],
+                "summary": "List the files of a deployment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only files whose path starts with this prefix, e.g. /assets/",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (default: 1)",
+                        "name": "pageNumber",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 25,
+                        "description": "Page size (default: 25, max: 100)",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.DeploymentFilePaged"
+                        }
+                    },
+                    "404": {
+                        "description": "Deployment not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/deployments/{id}/finalize": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deployments"
+                ],
+                "summary": "Finalize a delta upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.Deployment"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload session not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Contents still missing, keyed by digest",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/deployments/{id}/protection": {
+            "put": {
+                "description": "Replaces the deployment's basic auth users, which are required on its preview hostname instead of the project's. The production hostname keeps the project's protection.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deployments"
+                ],
+                "summary": "Protect a deployment with basic auth",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Users",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.SetDeploymentProtection"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.Deployment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Deployment not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deployments"
+                ],
+                "summary": "Remove basic auth from a deployment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.Deployment"
+                        }
+                    },
+                    "404": {
+                        "description": "Deployment not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/deployments/{id}/upload": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deployments"
+                ],
+                "summary": "Get a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.ResumableUpload"
+                        },
+                        "headers": {
+                            "Upload-Offset": {
+                                "type": "integer",
+                                "description": "Bytes received so far"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found or abandoned",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deployments"
+                ],
+                "summary": "Upload a chunk of a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk, which must equal the bytes received so far",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.ResumableUpload"
+                        },
+                        "headers": {
+                            "Upload-Offset": {
+                                "type": "integer",
+                                "description": "Bytes received so far"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing Upload-Offset header",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found or abandoned",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Offset does not match the bytes received, or another request is writing to the upload",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed or chunk extends past the declared size",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/deployments/{id}/upload/finalize": {
+            "post": {
+                "description": "A checksum mismatch fails the deployment, since the corrupted bytes cannot be located and resent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "deployments"
+                ],
+                "summary": "Finalize a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_deployment.Deployment"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload not found or abandoned",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Archive not fully received, or another request is writing to the upload",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed or archive does not match its checksum",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "List projects",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (default: 1)",
+                        "name": "pageNumber",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size (default: 10, max: 100)",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.ProjectPaged"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid parameters",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Create a project",
+                "parameters": [
+                    {
+                        "description": "Project Details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.CreateProject"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Get a project by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Delete a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Update a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated Project Details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.UpdateProject"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{id}/canary": {
+            "put": {
+                "description": "Sends the given percentage of production clients to another ready deployment. Requires a production deployment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Start or adjust a canary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Canary deployment and weight",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.SetCanary"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Project has no production deployment or canary deployment is not ready",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{id}/canary/abort": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Abort a canary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "No canary running",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{id}/canary/promote": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Promote a canary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "No canary running",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{id}/maintenance": {
+            "post": {
+                "description": "Accepts JSON, or a multipart form with an uploaded page file and repeated bypass_ips fields.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Enable maintenance mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Custom page and bypass list",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.EnableMaintenance"
+                        }
+                    },
+                    {
+                        "type": "file",
+                        "description": "Custom HTML page (multipart only)",
+                        "name": "page",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Disable maintenance mode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{id}/promote": {
+            "post": {
+                "description": "Serves the deployment on {project}.{domain} in addition to its preview hostname.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Promote a deployment to production",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Deployment to promote",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.PromoteDeployment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ready deployment not found in project",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{id}/protection": {
+            "put": {
+                "description": "Replaces the project's basic auth users. Scope \"previews\" leaves the production hostname public and \"production\" leaves preview hostnames public. Deployments with users of their own keep them on their preview hostname.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Protect a project with basic auth",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Scope and users",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.SetProtection"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "projects"
+                ],
+                "summary": "Remove basic auth from a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_resources_project.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_dimasbaguspm_infario_internal_gateway.AccessRules": {
+            "description": "CIDR allow and deny lists",
+            "type": "object",
+            "properties": {
+                "allow": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "deny": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_dimasbaguspm_infario_internal_gateway.HeaderRule": {
+            "description": "Response headers applied to a path pattern",
+            "type": "object",
+            "required": [
+                "headers",
+                "path"
+            ],
+            "properties": {
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_dimasbaguspm_infario_internal_gateway.RateLimit": {
+            "description": "Per-client request rate and connection limits",
+            "type": "object",
+            "properties": {
+                "burst": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1
+                },
+                "connections": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1
+                },
+                "requests_per_second": {
+                    "type": "integer",
+                    "maximum": 10000,
+                    "minimum": 1
+                }
+            }
+        },
+        "github_com_dimasbaguspm_infario_internal_gateway.RedirectRule": {
+            "description": "Redirect, rewrite or proxy rule",
+            "type": "object",
+            "required": [
+                "from",
+                "to"
+            ],
+            "properties": {
+                "force": {
+                    "description": "Apply even when a file exists at the path (\"301!\" in _redirects)",
+                    "type": "boolean"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "enum": [
+                        200,
+                        301,
+                        302,
+                        303,
+                        307,
+                        308,
+                        404,
+                        410
+                    ]
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_dimasbaguspm_infario_internal_gateway.Upstream": {
+            "description": "Backend receiving the requests under a path prefix",
+            "type": "object",
+            "required": [
+                "path",
+                "url"
+            ],
+            "properties": {
+                "connect_timeout": {
+                    "description": "Seconds; defaults to 5",
+                    "type": "integer",
+                    "maximum": 300,
+                    "minimum": 1
+                },
+                "headers": {
+                    "description": "Extra request headers sent to the upstream",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "health_path": {
+                    "description": "Probed before the deployment goes live; defaults to \"/\"",
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "preserve_host": {
+                    "description": "Forward the client's Host header instead of the upstream's",
+                    "type": "boolean"
+                },
+                "read_timeout": {
+                    "description": "Seconds between reads and writes; defaults to 60",
+                    "type": "integer",
+                    "maximum": 3600,
+                    "minimum": 1
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_dimasbaguspm_infario_internal_resources_project.Credential": {
+            "description": "Basic auth user",
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 8
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "github_com_dimasbaguspm_infario_pkgs_response.ErrorResponse": {
+            "description": "Standard API error response",
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "Detailed field errors",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "description": "Human readable summary",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP Status Code",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_resources_deployment.CreateDeltaUpload": {
+            "description": "Delta upload DTO listing every file of the deployment by content digest",
+            "type": "object",
+            "required": [
+                "files",
+                "hash",
+                "project_id"
+            ],
+            "properties": {
+                "entry_path": {
+                    "type": "string"
+                },
+                "files": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/internal_resources_deployment.DeltaFile"
+                    }
+                },
+                "hash": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "serving_mode": {
+                    "type": "string",
+                    "enum": [
+                        "spa",
+                        "static",
+                        "clean_urls"
+                    ]
+                }
+            }
+        },
+        "internal_resources_deployment.CreateProxyDeployment": {
+            "description": "Proxy deployment DTO; one upstream must cover the \"/\" path",
+            "type": "object",
+            "required": [
+                "hash",
+                "project_id",
+                "upstreams"
+            ],
+            "properties": {
+                "hash": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "upstreams": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.Upstream"
+                    }
+                }
+            }
+        },
+        "internal_resources_deployment.CreateResumableUpload": {
+            "description": "Resumable upload DTO declaring the archive's size and SHA-256 checksum up front",
+            "type": "object",
+            "required": [
+                "hash",
+                "project_id",
+                "sha256",
+                "size"
+            ],
+            "properties": {
+                "entry_path": {
+                    "type": "string"
+                },
+                "filename": {
+                    "description": "Name of the archive, e.g. \"site.tar.gz\"",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "hash": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "serving_mode": {
+                    "type": "string",
+                    "enum": [
+                        "spa",
+                        "static",
+                        "clean_urls"
+                    ]
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "description": "Bytes of the archive",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "internal_resources_deployment.DeltaFile": {
+            "description": "File of a deployment uploaded by content digest",
+            "type": "object",
+            "required": [
+                "path",
+                "sha256"
+            ],
+            "properties": {
+                "mode": {
+                    "description": "Defaults to 0644",
+                    "type": "string",
+                    "enum": [
+                        "0644",
+                        "0755"
+                    ]
+                },
+                "path": {
+                    "description": "Request path of the file, e.g. \"/assets/app.js\"",
+                    "type": "string",
+                    "maxLength": 1024
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "internal_resources_deployment.DeltaUpload": {
+            "description": "Pending deployment and the contents still to be uploaded before finalizing it",
+            "type": "object",
+            "properties": {
+                "deployment": {
+                    "$ref": "#/definitions/internal_resources_deployment.Deployment"
+                },
+                "missing": {
+                    "description": "Digests to upload to /deployments/{id}/blobs/{sha256}",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_resources_deployment.Deployment": {
+            "description": "Deployment entity representing a built artifact with content-addressable identifier",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "entry_path": {
+                    "description": "Entry file, or directory when ending with \"/\"",
+                    "type": "string"
+                },
+                "error_message": {
+                    "description": "Why processing failed when status is error",
+                    "type": "string"
+                },
+                "expired_at": {
                     "description": "Nullable: some builds may never expire",
                     "type": "string"
                 },
+                "file_count": {
+                    "description": "Set once extracted",
+                    "type": "integer"
+                },
                 "hash": {
                     "description": "The content-addressable identifier",
                     "type": "string"
                 },
+                "headers": {
+                    "description": "Parsed from the archive's _headers file",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.HeaderRule"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
+                "metadata": {
+                    "description": "Free-form labels from infario.json",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "preview_id": {
+                    "description": "Short DNS label unique within the project, used in the preview URL",
+                    "type": "string"
+                },
+                "preview_url": {
+                    "description": "URL serving this deployment under its preview ID",
+                    "type": "string"
+                },
+                "production": {
+                    "description": "Served as its project's production deployment",
+                    "type": "boolean"
+                },
+                "production_url": {
+                    "description": "Set while this deployment is the project's production deployment",
+                    "type": "string"
+                },
                 "project_id": {
                     "type": "string"
                 },
                 "project_name": {
                     "type": "string"
                 },
+                "protection": {
+                    "description": "Basic auth of the preview hostname, absent when it follows the project",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_resources_deployment.DeploymentProtection"
+                        }
+                    ]
+                },
+                "redirects": {
+                    "description": "Parsed from the archive's _redirects file",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.RedirectRule"
+                    }
+                },
+                "serving_mode": {
+                    "description": "spa, static or clean_urls; resolved during processing",
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
+                },
+                "total_size": {
+                    "description": "Bytes across all files, set once extracted",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "static or proxy",
+                    "type": "string"
+                },
+                "upstreams": {
+                    "description": "Path prefixes proxied to backends",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.Upstream"
+                    }
+                },
+                "url": {
+                    "description": "Production URL when served as production, otherwise the preview URL",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_resources_deployment.DeploymentDiff": {
+            "description": "Files added, removed and modified by a deployment compared with another one",
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_resources_deployment.FileChange"
+                    }
+                },
+                "against": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "modified": {
+                    "description": "Content or permissions changed",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_resources_deployment.FileChange"
+                    }
+                },
+                "removed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_resources_deployment.FileChange"
+                    }
+                },
+                "totals": {
+                    "$ref": "#/definitions/internal_resources_deployment.DiffTotals"
+                }
+            }
+        },
+        "internal_resources_deployment.DeploymentFile": {
+            "description": "File of a deployment with its content digest",
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "mode": {
+                    "description": "Octal permission bits, e.g. \"0644\"",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "Request path of the file, e.g. \"/assets/app.js\"",
+                    "type": "string"
+                },
+                "sha256": {
+                    "description": "Hex-encoded digest of the content",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_resources_deployment.DeploymentFilePaged": {
+            "description": "Paginated deployment file response with metadata",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_resources_deployment.DeploymentFile"
+                    }
+                },
+                "pageCount": {
+                    "type": "integer"
+                },
+                "pageNumber": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "totalCount": {
+                    "type": "integer"
                 }
             }
         },
@@ -500,6 +2110,150 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_resources_deployment.DeploymentProtection": {
+            "description": "Basic auth users required on a deployment's preview hostname instead of its project's",
+            "type": "object",
+            "properties": {
+                "usernames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_resources_deployment.DiffTotals": {
+            "description": "Counts and sizes of a deployment comparison",
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer"
+                },
+                "modified": {
+                    "type": "integer"
+                },
+                "new_size": {
+                    "type": "integer"
+                },
+                "old_size": {
+                    "description": "Bytes across all files of the deployment compared against",
+                    "type": "integer"
+                },
+                "removed": {
+                    "type": "integer"
+                },
+                "size_delta": {
+                    "type": "integer"
+                },
+                "unchanged": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_resources_deployment.FileChange": {
+            "description": "File that differs between two deployments",
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string"
+                },
+                "new_mode": {
+                    "type": "string"
+                },
+                "new_sha256": {
+                    "type": "string"
+                },
+                "new_size": {
+                    "description": "Unset for removed files",
+                    "type": "integer"
+                },
+                "old_mode": {
+                    "type": "string"
+                },
+                "old_sha256": {
+                    "type": "string"
+                },
+                "old_size": {
+                    "description": "Unset for added files",
+                    "type": "integer"
+                },
+                "patch": {
+                    "description": "Unified diff, only for text files up to 64 KiB when requested",
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "size_delta": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_resources_deployment.ResumableUpload": {
+            "description": "Pending deployment and how much of its archive has been received",
+            "type": "object",
+            "properties": {
+                "deployment": {
+                    "$ref": "#/definitions/internal_resources_deployment.Deployment"
+                },
+                "expires_at": {
+                    "description": "Abandoned unless a chunk is received before then",
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "offset": {
+                    "description": "Bytes received so far, where the next chunk must start",
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_resources_deployment.SetDeploymentProtection": {
+            "description": "Deployment basic auth DTO, replacing any existing users",
+            "type": "object",
+            "required": [
+                "credentials",
+                "id"
+            ],
+            "properties": {
+                "credentials": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_resources_project.Credential"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_resources_project.Canary": {
+            "description": "Canary split of production traffic",
+            "type": "object",
+            "properties": {
+                "deployment_id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "\"cookie\" or \"ip\"",
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "Percentage of clients sent to the canary deployment",
+                    "type": "integer"
+                }
+            }
+        },
         "internal_resources_project.CreateProject": {
             "description": "Project creation DTO",
             "type": "object",
@@ -507,10 +2261,112 @@ const docTemplate = `{
                 "name"
             ],
             "properties": {
+                "archive_formats": {
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "deployment_routing": {
+                    "description": "Defaults to false",
+                    "type": "boolean"
+                },
+                "headers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.HeaderRule"
+                    }
+                },
                 "name": {
                     "type": "string",
                     "maxLength": 100,
                     "minLength": 3
+                },
+                "preview_access": {
+                    "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.AccessRules"
+                },
+                "production_access": {
+                    "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.AccessRules"
+                },
+                "rate_limit": {
+                    "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.RateLimit"
+                },
+                "redirects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.RedirectRule"
+                    }
+                },
+                "serving_mode": {
+                    "type": "string",
+                    "enum": [
+                        "spa",
+                        "static",
+                        "clean_urls"
+                    ]
+                }
+            }
+        },
+        "internal_resources_project.Credential": {
+            "description": "Basic auth user",
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 8
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "internal_resources_project.EnableMaintenance": {
+            "description": "Maintenance mode DTO",
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "bypass_ips": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "html": {
+                    "description": "Custom 503 page; a default page is used when empty",
+                    "type": "string",
+                    "maxLength": 262144
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_resources_project.Maintenance": {
+            "description": "Maintenance mode taking every hostname of a project offline",
+            "type": "object",
+            "properties": {
+                "bypass_ips": {
+                    "description": "Addresses or CIDR ranges still served normally",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "custom_page": {
+                    "type": "boolean"
+                },
+                "enabled_at": {
+                    "type": "string"
                 }
             }
         },
@@ -518,20 +2374,109 @@ const docTemplate = `{
             "description": "Project entity representing a project with its metadata",
             "type": "object",
             "properties": {
+                "archive_formats": {
+                    "description": "Upload formats accepted, every supported format when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "canary": {
+                    "description": "Set while production traffic is split",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_resources_project.Canary"
+                        }
+                    ]
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "deleted_at": {
                     "type": "string"
                 },
+                "deployment_routing": {
+                    "description": "X-Infario-Deployment header and preview cookie routing on the production hostname",
+                    "type": "boolean"
+                },
+                "headers": {
+                    "description": "Defaults overridden by each deployment's _headers rules",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.HeaderRule"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
+                "maintenance": {
+                    "description": "Set while every hostname serves a 503 page",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_resources_project.Maintenance"
+                        }
+                    ]
+                },
                 "name": {
                     "type": "string"
                 },
+                "preview_access": {
+                    "description": "CIDR rules for preview hostnames",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.AccessRules"
+                        }
+                    ]
+                },
+                "production_access": {
+                    "description": "CIDR rules for the production hostname",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.AccessRules"
+                        }
+                    ]
+                },
+                "production_deployment_id": {
+                    "description": "Deployment served on {project}.{domain}",
+                    "type": "string"
+                },
+                "production_url": {
+                    "description": "URL serving the production deployment",
+                    "type": "string"
+                },
+                "protection": {
+                    "description": "Basic auth settings, absent when public",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/internal_resources_project.Protection"
+                        }
+                    ]
+                },
+                "rate_limit": {
+                    "description": "Zero values use the gateway defaults",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.RateLimit"
+                        }
+                    ]
+                },
+                "redirects": {
+                    "description": "Applied after each deployment's _redirects rules",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.RedirectRule"
+                    }
+                },
+                "serving_mode": {
+                    "description": "Default serving mode for new deployments",
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
+                },
+                "url": {
+                    "description": "Public URL of the project, set once a deployment is promoted",
+                    "type": "string"
                 }
             }
         },
@@ -559,6 +2504,99 @@ const docTemplate = `{
                 }
             }
         },
+        "internal_resources_project.PromoteDeployment": {
+            "description": "Production promotion DTO",
+            "type": "object",
+            "required": [
+                "deployment_id",
+                "id"
+            ],
+            "properties": {
+                "deployment_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_resources_project.Protection": {
+            "description": "Basic auth protection of a project's hostnames",
+            "type": "object",
+            "properties": {
+                "scope": {
+                    "description": "\"all\", \"previews\" (production hostname stays public) or \"production\" (previews stay public)",
+                    "type": "string"
+                },
+                "usernames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_resources_project.SetCanary": {
+            "description": "Canary DTO",
+            "type": "object",
+            "required": [
+                "deployment_id",
+                "id",
+                "weight"
+            ],
+            "properties": {
+                "deployment_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Defaults to cookie",
+                    "type": "string",
+                    "enum": [
+                        "cookie",
+                        "ip"
+                    ]
+                },
+                "weight": {
+                    "type": "integer",
+                    "maximum": 99,
+                    "minimum": 1
+                }
+            }
+        },
+        "internal_resources_project.SetProtection": {
+            "description": "Basic auth protection DTO, replacing any existing users",
+            "type": "object",
+            "required": [
+                "credentials",
+                "id",
+                "scope"
+            ],
+            "properties": {
+                "credentials": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "$ref": "#/definitions/internal_resources_project.Credential"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string",
+                    "enum": [
+                        "all",
+                        "previews",
+                        "production"
+                    ]
+                }
+            }
+        },
         "internal_resources_project.UpdateProject": {
             "description": "Project update DTO",
             "type": "object",
@@ -566,6 +2604,24 @@ const docTemplate = `{
                 "id"
             ],
             "properties": {
+                "archive_formats": {
+                    "description": "Replaces the list when set; empty allows every format",
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "deployment_routing": {
+                    "type": "boolean"
+                },
+                "headers": {
+                    "description": "Replaces all rules when set",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.HeaderRule"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
@@ -573,6 +2629,45 @@ const docTemplate = `{
                     "type": "string",
                     "maxLength": 100,
                     "minLength": 3
+                },
+                "preview_access": {
+                    "description": "Replaces both lists when set",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.AccessRules"
+                        }
+                    ]
+                },
+                "production_access": {
+                    "description": "Replaces both lists when set",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.AccessRules"
+                        }
+                    ]
+                },
+                "rate_limit": {
+                    "description": "Replaces all limits when set",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.RateLimit"
+                        }
+                    ]
+                },
+                "redirects": {
+                    "description": "Replaces all rules when set",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_dimasbaguspm_infario_internal_gateway.RedirectRule"
+                    }
+                },
+                "serving_mode": {
+                    "type": "string",
+                    "enum": [
+                        "spa",
+                        "static",
+                        "clean_urls"
+                    ]
                 }
             }
         }
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+)
+
+// AccessRules restricts which client addresses may reach a hostname. Denied ranges are always
+// rejected; when Allow is not empty, every address outside of it is rejected as well.
+// @Description CIDR allow and deny lists
+// @Name AccessRules
+type AccessRules struct {
+	Allow []string `json:"allow" validate:"omitempty,max=100,dive,cidr"`
+	Deny  []string `json:"deny" validate:"omitempty,max=100,dive,cidr"`
+}
+
+// directives renders the rules as nginx allow/deny directives. nginx stops at the first matching
+// directive, so deny ranges come first and a closing "deny all" enforces the allowlist.
+func (a AccessRules) directives() string {
+	config := ""
+	for _, cidr := range a.Deny {
+		config += fmt.Sprintf("    deny %s;\n", cidr)
+	}
+	for _, cidr := range a.Allow {
+		config += fmt.Sprintf("    allow %s;\n", cidr)
+	}
+	if len(a.Allow) > 0 {
+		config += "    deny all;\n"
+	}
+	if config != "" {
+		config += "\n"
+	}
+	return config
+}
+
+// permits reports whether a client address passes the rules, mirroring the nginx directives.
+func (a AccessRules) permits(ip net.IP) bool {
+	if ip == nil {
+		return len(a.Deny) == 0 && len(a.Allow) == 0
+	}
+	if containsIP(a.Deny, ip) {
+		return false
+	}
+	return len(a.Allow) == 0 || containsIP(a.Allow, ip)
+}
+
+// containsIP reports whether any of the addresses or CIDR ranges contains the address.
+func containsIP(ranges []string, ip net.IP) bool {
+	for _, r := range ranges {
+		if _, network, err := net.ParseCIDR(r); err == nil && network.Contains(ip) {
+			return true
+		}
+		if other := net.ParseIP(r); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
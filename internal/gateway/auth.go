@@ -0,0 +1,212 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Auth scopes decide which hostnames of a protected project require credentials.
+const (
+	// AuthScopeAll protects the production hostname and every preview hostname.
+	AuthScopeAll = "all"
+	// AuthScopePreviews protects preview hostnames and leaves the production hostname public.
+	AuthScopePreviews = "previews"
+	// AuthScopeProduction protects the production hostname and leaves preview hostnames public.
+	AuthScopeProduction = "production"
+)
+
+// Credential is a basic auth user with a bcrypt password hash, as written to htpasswd files.
+type Credential struct {
+	Username     string
+	PasswordHash string
+}
+
+// HashPassword hashes a password for storage and for nginx's auth_basic_user_file.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// protects reports whether the project's credentials are required on a hostname of the given kind.
+// Deployments with credentials of their own replace them on their preview hostname.
+func (p GatewayProject) protects(production bool) bool {
+	if len(p.Credentials) == 0 {
+		return false
+	}
+	switch p.AuthScope {
+	case AuthScopePreviews:
+		return !production
+	case AuthScopeProduction:
+		return production
+	default:
+		return true
+	}
+}
+
+// htpasswd renders credentials in the htpasswd format, one "user:hash" line each.
+func htpasswd(credentials []Credential) string {
+	var b strings.Builder
+	for _, c := range credentials {
+		fmt.Fprintf(&b, "%s:%s\n", c.Username, c.PasswordHash)
+	}
+	return b.String()
+}
+
+// authRealm is the realm shown in browser credential prompts.
+const authRealm = "Restricted"
+
+// authBasic renders the server-level directives requiring credentials from the project's htpasswd file.
+func authBasic(userFile string) string {
+	config := fmt.Sprintf("    auth_basic \"%s\";\n", authRealm)
+	config += fmt.Sprintf("    auth_basic_user_file %s;\n\n", userFile)
+	return config
+}
+
+// projectHtpasswd names the htpasswd file of a project's users.
+func projectHtpasswd(projectID string) string {
+	return projectID + ".htpasswd"
+}
+
+// deploymentHtpasswd names the htpasswd file of a deployment's own users.
+func deploymentHtpasswd(projectID, deploymentID string) string {
+	return projectID + "-" + deploymentID + ".htpasswd"
+}
+
+// writeHtpasswd writes the htpasswd files of a project and of its deployments with users of their own
+// next to its nginx config, removing those no longer needed.
+func (ng *NginxGateway) writeHtpasswd(project GatewayProject, deployments []GatewayDeployment) error {
+	stale, err := filepath.Glob(filepath.Join(ng.configDir, deploymentHtpasswd(project.ID, "*")))
+	if err != nil {
+		return fmt.Errorf("failed to list htpasswd files: %w", err)
+	}
+	keep := map[string]bool{}
+
+	files := map[string][]Credential{projectHtpasswd(project.ID): project.Credentials}
+	for _, dep := range deployments {
+		if len(dep.Credentials) > 0 {
+			files[deploymentHtpasswd(project.ID, dep.ID)] = dep.Credentials
+		}
+	}
+
+	for name, credentials := range files {
+		htpasswdPath := filepath.Join(ng.configDir, name)
+		if len(credentials) == 0 {
+			stale = append(stale, htpasswdPath)
+			continue
+		}
+		if err := os.WriteFile(htpasswdPath, []byte(htpasswd(credentials)), 0644); err != nil {
+			return fmt.Errorf("failed to write htpasswd file: %w", err)
+		}
+		keep[htpasswdPath] = true
+	}
+
+	for _, htpasswdPath := range stale {
+		if keep[htpasswdPath] {
+			continue
+		}
+		if err := os.Remove(htpasswdPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove htpasswd file: %w", err)
+		}
+	}
+	return nil
+}
+
+const (
+	// verifiedTTL is how long a verified username and password are accepted without rerunning bcrypt.
+	verifiedTTL = time.Minute
+	// verifiedMax bounds the number of verified credentials remembered at once.
+	verifiedMax = 1024
+)
+
+// credentialCache remembers recently verified credentials and bounds how many bcrypt comparisons run
+// at once, so requests to protected sites cannot keep every CPU busy hashing.
+type credentialCache struct {
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]time.Time // Digest of user, hash and password to expiry
+	slots    chan struct{}
+}
+
+func newCredentialCache() *credentialCache {
+	return &credentialCache{
+		verified: map[[sha256.Size]byte]time.Time{},
+		slots:    make(chan struct{}, runtime.GOMAXPROCS(0)),
+	}
+}
+
+// authorized reports whether a request carries credentials matching one of the given users.
+// The stored hash is part of the cache key, so changing a password invalidates the cached entry.
+func (c *credentialCache) authorized(r *http.Request, credentials []Credential) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	for _, cred := range credentials {
+		if cred.Username != username {
+			continue
+		}
+
+		key := sha256.Sum256([]byte(cred.Username + "\x00" + cred.PasswordHash + "\x00" + password))
+		if c.fresh(key) {
+			return true
+		}
+
+		select {
+		case c.slots <- struct{}{}:
+		case <-r.Context().Done():
+			return false
+		}
+		err := bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(password))
+		<-c.slots
+		if err != nil {
+			return false
+		}
+
+		c.remember(key)
+		return true
+	}
+	return false
+}
+
+// fresh reports whether a digest was verified within verifiedTTL.
+func (c *credentialCache) fresh(key [sha256.Size]byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiry, ok := c.verified[key]
+	if ok && time.Now().After(expiry) {
+		delete(c.verified, key)
+		return false
+	}
+	return ok
+}
+
+// remember caches a verified digest, evicting expired entries, or all of them, when full.
+func (c *credentialCache) remember(key [sha256.Size]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.verified) >= verifiedMax {
+		for k, expiry := range c.verified {
+			if now.After(expiry) {
+				delete(c.verified, k)
+			}
+		}
+		if len(c.verified) >= verifiedMax {
+			clear(c.verified)
+		}
+	}
+	c.verified[key] = now.Add(verifiedTTL)
+}
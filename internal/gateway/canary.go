@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+)
+
+// Canary keys decide how clients are assigned to a side of the split.
+const (
+	// CanaryKeyCookie assigns clients by a random id kept in a cookie.
+	CanaryKeyCookie = "cookie"
+	// CanaryKeyIP assigns clients by address, for clients that drop cookies.
+	CanaryKeyIP = "ip"
+)
+
+// canaryCookie keeps a client on the same side of a canary across requests.
+const canaryCookie = "infario_canary"
+
+// Canary sends a share of production traffic to another ready deployment.
+type Canary struct {
+	DeploymentID string
+	Weight       int    // Percentage of clients sent to the canary deployment, 1-99
+	Key          string // CanaryKeyCookie or CanaryKeyIP
+}
+
+// canaryKeyVariable names the variable clients are split on.
+func canaryKeyVariable(projectID string) string {
+	return "$" + variablePrefix(projectID) + "_canary_key"
+}
+
+// canarySplit renders the http-level blocks assigning each client to the production or canary deployment,
+// storing the chosen internal hostname in variable. In cookie mode, clients without the cookie are keyed
+// by their request id, which the cookie then keeps.
+func canarySplit(project GatewayProject, productionID, canaryID, variable string) string {
+	key := "$remote_addr"
+	config := ""
+
+	if project.Canary.Key != CanaryKeyIP {
+		key = canaryKeyVariable(project.ID)
+		config += fmt.Sprintf("map $cookie_%s %s {\n", canaryCookie, key)
+		config += "    \"\" $request_id;\n"
+		config += fmt.Sprintf("    default $cookie_%s;\n", canaryCookie)
+		config += "}\n\n"
+	}
+
+	config += fmt.Sprintf("split_clients \"%s\" %s {\n", key, variable)
+	config += fmt.Sprintf("    %d%% %s;\n", project.Canary.Weight, internalHost(canaryID))
+	config += fmt.Sprintf("    * %s;\n", internalHost(productionID))
+	config += "}\n\n"
+	return config
+}
+
+// canaryCookieHeader renders the directive keeping cookie-keyed clients on their side of the split.
+func canaryCookieHeader(project GatewayProject) string {
+	if project.Canary.Key == CanaryKeyIP {
+		return ""
+	}
+	return fmt.Sprintf("    add_header Set-Cookie \"%s=%s; Path=/; Max-Age=86400; HttpOnly; SameSite=Lax\" always;\n\n",
+		canaryCookie, canaryKeyVariable(project.ID))
+}
+
+// canarySide picks the deployment a client is served on the production hostname by the static gateway.
+// Clients are split on a hash of their key, so each stays on one side; in cookie mode clients without
+// the cookie get a random key, which the cookie then keeps under base.
+func canarySide(w http.ResponseWriter, r *http.Request, canary Canary, production, canaryDep *GatewayDeployment, base string) *GatewayDeployment {
+	var key string
+	if canary.Key == CanaryKeyIP {
+		if ip := remoteIP(r); ip != nil {
+			key = ip.String()
+		}
+	} else {
+		if cookie, err := r.Cookie(canaryCookie); err == nil {
+			key = cookie.Value
+		}
+		if key == "" {
+			key = rand.Text()
+		}
+		http.SetCookie(w, &http.Cookie{Name: canaryCookie, Value: key, Path: base, MaxAge: 86400, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	if int(hash.Sum32()%100) < canary.Weight {
+		return canaryDep
+	}
+	return production
+}
@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HeaderRule sets response headers on requests whose path matches Path, following Netlify's
+// _headers semantics. Paths use the same :placeholder and trailing * syntax as redirect sources.
+// @Description Response headers applied to a path pattern
+// @Name HeaderRule
+type HeaderRule struct {
+	Path    string            `json:"path" validate:"required"`
+	Headers map[string]string `json:"headers" validate:"required,min=1"`
+}
+
+var (
+	headerNameChars = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+	// reservedHeaders are managed by nginx and must not be overridden per path.
+	reservedHeaders = map[string]bool{
+		"connection":        true,
+		"content-encoding":  true,
+		"content-length":    true,
+		"content-range":     true,
+		"date":              true,
+		"server":            true,
+		"transfer-encoding": true,
+	}
+)
+
+// ParseHeaders reads rules in the _headers file format: an unindented path line followed by
+// indented "Name: value" lines. Blank lines and # comments are ignored, and a header repeated
+// for the same path is joined with ", ". Errors report the offending line number.
+func ParseHeaders(r io.Reader) ([]HeaderRule, error) {
+	var rules []HeaderRule
+	var current *HeaderRule
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		if !indented {
+			if err := validatePathPattern(text); err != nil {
+				return nil, fmt.Errorf("line %d: path %w", line, err)
+			}
+			rules = append(rules, HeaderRule{Path: text, Headers: map[string]string{}})
+			current = &rules[len(rules)-1]
+			continue
+		}
+
+		if current == nil {
+			return nil, fmt.Errorf("line %d: header defined before any path", line)
+		}
+
+		name, value, found := strings.Cut(text, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"Name: value\"", line)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if err := validateHeader(name, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if existing, ok := current.Headers[name]; ok {
+			value = existing + ", " + value
+		}
+		current.Headers[name] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read headers: %w", err)
+	}
+
+	for _, rule := range rules {
+		if len(rule.Headers) == 0 {
+			return nil, fmt.Errorf("path %s has no headers", rule.Path)
+		}
+	}
+
+	return rules, nil
+}
+
+// Validate checks that a rule can be rendered into nginx configuration safely.
+func (h HeaderRule) Validate() error {
+	if err := validatePathPattern(h.Path); err != nil {
+		return fmt.Errorf("path %w", err)
+	}
+	if len(h.Headers) == 0 {
+		return fmt.Errorf("path %s has no headers", h.Path)
+	}
+	for name, value := range h.Headers {
+		if err := validateHeader(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateHeaders validates every rule, reporting the index of the first invalid one.
+func ValidateHeaders(rules []HeaderRule) error {
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateHeader rejects header names and values that cannot be expressed in a quoted nginx string.
+func validateHeader(name, value string) error {
+	if !headerNameChars.MatchString(name) {
+		return fmt.Errorf("header name %q may only contain letters, digits and hyphens", name)
+	}
+	if reservedHeaders[strings.ToLower(name)] {
+		return fmt.Errorf("header %s is managed by the gateway and cannot be set", name)
+	}
+	if value == "" {
+		return fmt.Errorf("header %s has an empty value", name)
+	}
+	for _, c := range value {
+		if c < 0x20 || c == 0x7f || c == '$' || c == '\\' {
+			return fmt.Errorf("header %s value must not contain control characters, $ or backslashes", name)
+		}
+	}
+	return nil
+}
+
+// headerMaps renders one map per header name, choosing its value from the first rule whose path
+// matches the request. Earlier rules win, so deployment rules listed before project defaults override them.
+// It returns the map blocks (http level) and the add_header directives (server level).
+func headerMaps(prefix string, rules []HeaderRule) (maps string, directives string) {
+	var names []string
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		for name := range rule.Headers {
+			key := strings.ToLower(name)
+			if !seen[key] {
+				seen[key] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+
+	for i, name := range names {
+		variable := fmt.Sprintf("$%s_header_%d", prefix, i)
+
+		maps += fmt.Sprintf("map $request_uri %s {\n", variable)
+		maps += "    default \"\";\n"
+		for _, rule := range rules {
+			for header, value := range rule.Headers {
+				if strings.EqualFold(header, name) {
+					pattern := pathPattern(rule.Path, false) + `(?:\?.*)?$`
+					maps += fmt.Sprintf("    \"~%s\" \"%s\";\n", pattern, strings.ReplaceAll(value, `"`, `\"`))
+				}
+			}
+		}
+		maps += "}\n\n"
+
+		directives += fmt.Sprintf("    add_header %s %s always;\n", name, variable)
+	}
+
+	if directives != "" {
+		directives += "\n"
+	}
+	return maps, directives
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// sharedConfigFile holds the zones shared by every project. Its name sorts before the per-project
+// files so the zones are declared before any server references them.
+const sharedConfigFile = "00-infario.conf"
+
+const (
+	defaultRequestZone    = "infario_req"
+	defaultConnectionZone = "infario_conn"
+
+	// limitKey counts clients per hostname, so traffic to one project never spends another's allowance
+	// in the shared zones.
+	limitKey = "$binary_remote_addr$server_name"
+)
+
+// RateLimit caps request rate and concurrent connections per client address and hostname.
+// Zero values fall back to the gateway defaults.
+// @Description Per-client request rate and connection limits
+// @Name RateLimit
+type RateLimit struct {
+	RequestsPerSecond int `json:"requests_per_second,omitempty" validate:"omitempty,min=1,max=10000"`
+	Burst             int `json:"burst,omitempty" validate:"omitempty,min=1,max=10000"`
+	Connections       int `json:"connections,omitempty" validate:"omitempty,min=1,max=10000"`
+}
+
+// withDefaults fills unset limits from the gateway defaults.
+func (l RateLimit) withDefaults(defaults RateLimit) RateLimit {
+	if l.RequestsPerSecond == 0 {
+		l.RequestsPerSecond = defaults.RequestsPerSecond
+	}
+	if l.Burst == 0 {
+		l.Burst = defaults.Burst
+	}
+	if l.Connections == 0 {
+		l.Connections = defaults.Connections
+	}
+	return l
+}
+
+// WriteSharedConfig writes the include file declaring the default limit zones, the connection
+// upgrade map used by upstreams and, with path routing, the server of the bare domain.
+func (ng *NginxGateway) WriteSharedConfig() error {
+	if ng.limits.RequestsPerSecond < 1 || ng.limits.Connections < 1 || ng.limits.Burst < 0 {
+		return fmt.Errorf("invalid default limits: %d requests per second, burst %d and %d connections", ng.limits.RequestsPerSecond, ng.limits.Burst, ng.limits.Connections)
+	}
+
+	if err := os.MkdirAll(ng.configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	config := "# Auto-generated nginx config shared by all projects\n\n"
+	config += fmt.Sprintf("limit_req_zone %s zone=%s:10m rate=%dr/s;\n", limitKey, defaultRequestZone, ng.limits.RequestsPerSecond)
+	config += fmt.Sprintf("limit_conn_zone %s zone=%s:10m;\n", limitKey, defaultConnectionZone)
+	config += "limit_req_status 429;\n"
+	config += "limit_conn_status 429;\n"
+
+	// Upstreams forward websocket upgrades and keep other connections closable
+	config += "\nmap $http_upgrade $connection_upgrade {\n"
+	config += "    default upgrade;\n"
+	config += "    \"\" close;\n"
+	config += "}\n"
+
+	if ng.routing.Mode == RoutingPath {
+		if err := os.MkdirAll(filepath.Join(ng.configDir, pathRoutesDir), 0755); err != nil {
+			return fmt.Errorf("failed to create path routes directory: %w", err)
+		}
+		config += ng.pathServer()
+	}
+
+	configPath := filepath.Join(ng.configDir, sharedConfigFile)
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		return fmt.Errorf("failed to write shared config file: %w", err)
+	}
+
+	return nil
+}
+
+// requestZone returns the limit_req zone serving a project, declaring a dedicated one when the
+// project's rate differs from the default. nginx fixes the rate per zone, not per directive.
+func (ng *NginxGateway) requestZone(project GatewayProject) (zone string, declaration string) {
+	limits := project.RateLimit.withDefaults(ng.limits)
+	if limits.RequestsPerSecond == ng.limits.RequestsPerSecond {
+		return defaultRequestZone, ""
+	}
+
+	zone = variablePrefix(project.ID) + "_req"
+	declaration = fmt.Sprintf("limit_req_zone %s zone=%s:1m rate=%dr/s;\n\n", limitKey, zone, limits.RequestsPerSecond)
+	return zone, declaration
+}
+
+// limitDirectives renders the server-level limit_req and limit_conn directives of a project.
+func (ng *NginxGateway) limitDirectives(project GatewayProject, zone string) string {
+	limits := project.RateLimit.withDefaults(ng.limits)
+
+	config := fmt.Sprintf("    limit_req zone=%s burst=%d nodelay;\n", zone, limits.Burst)
+	config += fmt.Sprintf("    limit_conn %s %d;\n\n", defaultConnectionZone, limits.Connections)
+	return config
+}
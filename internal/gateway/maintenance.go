@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defaultMaintenancePage is served while a project without a custom page is in maintenance.
+const defaultMaintenancePage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Down for maintenance</title>
+<style>body{font-family:system-ui,sans-serif;display:flex;min-height:100vh;margin:0;align-items:center;justify-content:center;color:#222}main{text-align:center;padding:2rem}</style>
+</head>
+<body>
+<main>
+<h1>Down for maintenance</h1>
+<p>This site is temporarily unavailable. Please check back soon.</p>
+</main>
+</body>
+</html>
+`
+
+// Maintenance takes every hostname of a project offline with a 503 page.
+type Maintenance struct {
+	Page      string   // Custom HTML; the default page is used when empty
+	BypassIPs []string // Addresses or CIDR ranges still served normally
+}
+
+// maintenancePageFile returns the name of a project's maintenance page inside the config directory.
+func maintenancePageFile(projectID string) string {
+	return projectID + ".maintenance.html"
+}
+
+// writeMaintenancePage writes or removes the maintenance page of a project next to its nginx config.
+func (ng *NginxGateway) writeMaintenancePage(project GatewayProject) error {
+	pagePath := filepath.Join(ng.configDir, maintenancePageFile(project.ID))
+
+	if project.Maintenance == nil {
+		if err := os.Remove(pagePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove maintenance page: %w", err)
+		}
+		return nil
+	}
+
+	page := project.Maintenance.Page
+	if page == "" {
+		page = defaultMaintenancePage
+	}
+	if err := os.WriteFile(pagePath, []byte(page), 0644); err != nil {
+		return fmt.Errorf("failed to write maintenance page: %w", err)
+	}
+	return nil
+}
+
+// maintenanceGeo renders the http-level geo block flagging clients that must see the maintenance page.
+func maintenanceGeo(variable string, m *Maintenance) string {
+	config := fmt.Sprintf("geo %s {\n", variable)
+	config += "    default 1;\n"
+	for _, ip := range m.BypassIPs {
+		config += fmt.Sprintf("    %s 0;\n", ip)
+	}
+	config += "}\n\n"
+	return config
+}
+
+// maintenanceServer renders the server-level directives answering flagged clients with the page.
+// The check runs in the server rewrite phase, before any location, auth or access rule. The page is
+// served from a named location because redirects to it skip that phase and cannot loop.
+func maintenanceServer(variable, projectID string) string {
+	config := "    error_page 503 @maintenance;\n"
+	config += fmt.Sprintf("    if (%s) {\n", variable)
+	config += "        return 503;\n"
+	config += "    }\n\n"
+	config += "    location @maintenance {\n"
+	config += fmt.Sprintf("        root %s;\n", nginxConfigDir)
+	config += fmt.Sprintf("        try_files /%s =503;\n", maintenancePageFile(projectID))
+	config += "        add_header Cache-Control \"no-store\" always;\n"
+	config += "        add_header Retry-After 300 always;\n"
+	config += "    }\n\n"
+	return config
+}
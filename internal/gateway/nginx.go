@@ -3,6 +3,7 @@ package gateway
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -11,9 +12,48 @@ import (
 type GatewayDeployment struct {
 	ID          string
 	Hash        string
+	PreviewID   string // DNS label naming the deployment in its preview hostname and path
 	ProjectID   string
 	ProjectName string
 	EntryPath   *string
+	ServingMode string
+	Redirects   []RedirectRule
+	Headers     []HeaderRule
+	Upstreams   []Upstream   // Path prefixes proxied to backends; "/" replaces the files entirely
+	Credentials []Credential // Required on the preview hostname instead of the project's users, if any
+	Access      AccessRules  // Enforced by the static gateway on this hostname
+	Maintenance *Maintenance // Served by the static gateway instead of files, if set
+}
+
+// GatewayProject represents project-wide settings applied to every deployment's server block.
+type GatewayProject struct {
+	ID        string
+	Name      string
+	Redirects []RedirectRule // Evaluated after the deployment's own rules
+	Headers   []HeaderRule   // Defaults overridden by the deployment's own rules
+
+	ProductionDeploymentID string       // Deployment served on {project}.{domain}, if any
+	Credentials            []Credential // Basic auth users; empty leaves the project public
+	AuthScope              string       // AuthScopeAll, AuthScopePreviews or AuthScopeProduction
+	ProductionAccess       AccessRules  // Applied on the production hostname
+	PreviewAccess          AccessRules  // Applied on every preview hostname
+	RateLimit              RateLimit    // Overrides of the gateway's default limits
+	Maintenance            *Maintenance // Serves a 503 page on every hostname when set
+	Canary                 *Canary      // Splits production traffic with another deployment when set
+	DeploymentRouting      bool         // Lets clients pick a deployment on the production hostname by header or cookie
+}
+
+// nginxConfigDir is where nginx sees the gateway's ConfigDir.
+const nginxConfigDir = "/etc/nginx/conf.d"
+
+// NginxConfig configures where and how the gateway writes nginx configuration.
+type NginxConfig struct {
+	ConfigDir  string    // Directory nginx includes *.conf files from
+	Domain     string    // Base domain deployments are served under
+	StorageDir string    // Local directory nginx serves deployment files from, mounted at /storage
+	Resolver   string    // DNS resolver nginx uses for proxy rules, e.g. Docker's 127.0.0.11
+	Limits     RateLimit // Default limits for projects without their own
+	Routing    Routing   // How deployments are addressed on Domain
 }
 
 // NginxGateway manages dynamic nginx configuration generation.
@@ -21,20 +61,33 @@ type NginxGateway struct {
 	configDir  string
 	domain     string
 	storageDir string
+	resolver   string
+	limits     RateLimit
+	routing    Routing
+}
+
+// StorageDir returns the local directory nginx serves deployment files from.
+func (ng *NginxGateway) StorageDir() string {
+	return ng.storageDir
 }
 
 // NewNginxGateway creates a new nginx gateway.
-func NewNginxGateway(configDir, domain, storageDir string) *NginxGateway {
+func NewNginxGateway(cfg NginxConfig) *NginxGateway {
 	return &NginxGateway{
-		configDir:  configDir,
-		domain:     domain,
-		storageDir: storageDir,
+		configDir:  cfg.ConfigDir,
+		domain:     cfg.Domain,
+		storageDir: cfg.StorageDir,
+		resolver:   cfg.Resolver,
+		limits:     cfg.Limits,
+		routing:    cfg.Routing,
 	}
 }
 
 // WriteProjectConfig generates and writes nginx configuration for a project's deployments.
 // If deployments list is empty, removes the config file instead.
-func (ng *NginxGateway) WriteProjectConfig(projectID, projectName string, deployments []GatewayDeployment) error {
+func (ng *NginxGateway) WriteProjectConfig(project GatewayProject, deployments []GatewayDeployment) error {
+	projectID, projectName := project.ID, project.Name
+
 	// If no deployments, remove the config file
 	if len(deployments) == 0 {
 		return ng.RemoveProjectConfig(projectID)
@@ -45,63 +98,52 @@ func (ng *NginxGateway) WriteProjectConfig(projectID, projectName string, deploy
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	// The htpasswd file and maintenance page must exist before the config referencing them is loaded
+	if err := ng.writeHtpasswd(project, deployments); err != nil {
+		return err
+	}
+	if err := ng.writeMaintenancePage(project); err != nil {
+		return err
+	}
+
 	// Build nginx server block
 	config := fmt.Sprintf("# Auto-generated nginx config for project: %s\n", projectName)
 	config += fmt.Sprintf("# Generated for deployments: %d\n\n", len(deployments))
 
+	zone, zoneDeclaration := ng.requestZone(project)
+	config += zoneDeclaration
+	limitDirectives := ng.limitDirectives(project, zone)
+
+	if project.Maintenance != nil {
+		config += maintenanceGeo(maintenanceVariable(project.ID), project.Maintenance)
+	}
+
+	production, canary := productionTargets(project, deployments)
+	proxied := productionProxied(project, production, canary)
+	// Requests pinned to a deployment are proxied to its preview server over loopback
+	pinnable := proxied && project.DeploymentRouting
+
 	for _, dep := range deployments {
-		// Server block
-		serverName := fmt.Sprintf("%s.%s.%s", dep.Hash, projectName, ng.domain)
-		config += fmt.Sprintln("server {\n")
-		config += fmt.Sprintf("    server_name %s;\n", serverName)
-		config += fmt.Sprintf("    listen 80;\n\n")
-
-		// Deployment directory
-		deploymentDir := fmt.Sprintf("/storage/deployments/%s/%s", dep.ProjectID, dep.ID)
-
-		// Location block - handle both file and directory entry paths
-		if dep.EntryPath != nil && *dep.EntryPath != "" && *dep.EntryPath != "/" {
-			entryPath := *dep.EntryPath
-			// Check if entry path is a file (has a file extension or looks like a file path)
-			isFile := strings.Contains(entryPath, ".") && !strings.HasSuffix(entryPath, "/")
-
-			if isFile {
-				// Entry path is a file - extract directory and filename
-				dirPart := filepath.Dir(entryPath)
-				filePart := filepath.Base(entryPath)
-
-				// Normalize "/" directory
-				if dirPart == "." {
-					dirPart = ""
-				}
-
-				rootPath := deploymentDir
-				if dirPart != "" && dirPart != "/" {
-					rootPath = deploymentDir + dirPart + "/"
-				} else if dirPart == "/" {
-					rootPath = deploymentDir + "/"
-				}
-
-				config += fmt.Sprintf("    location / {\n")
-				config += fmt.Sprintf("        alias %s;\n", rootPath)
-				config += fmt.Sprintf("        try_files $uri /%s =404;\n", filePart)
-				config += fmt.Sprintf("    }\n\n")
-			} else {
-				// Entry path is a directory - serve with path prefix
-				config += fmt.Sprintf("    location %s {\n", entryPath)
-				config += fmt.Sprintf("        alias %s;\n", deploymentDir+entryPath)
-				config += fmt.Sprintf("        try_files $uri =404;\n")
-				config += fmt.Sprintf("    }\n\n")
-			}
-		} else {
-			// No entry path or root entry path - serve from deployment root
-			config += fmt.Sprintf("    location / {\n")
-			config += fmt.Sprintf("        root %s;\n", deploymentDir)
-			config += fmt.Sprintf("        index index.html;\n")
-			config += fmt.Sprintf("    }\n\n")
+		// Header rules: the deployment's own first so they override project defaults
+		headers := append(append([]HeaderRule{}, dep.Headers...), project.Headers...)
+		headerMapBlocks, headerDirectives := headerMaps(variablePrefix(dep.ID), headers)
+		config += headerMapBlocks
+
+		site := ng.siteDirectives(project, dep, headerDirectives)
+		config += ng.serverBlock(previewHost(dep.PreviewID, projectName, ng.domain), pinnable, ng.frontDoor(project, &dep)+limitDirectives, site)
+
+		switch {
+		case proxied && (dep.ID == production.ID || (canary != nil && dep.ID == canary.ID)):
+			// Served internally and reached through the production proxy below
+			config += internalServerBlock(dep.ID, site)
+		case !proxied && production != nil && dep.ID == production.ID:
+			config += ng.serverBlock(productionHost(projectName, ng.domain), false, ng.frontDoor(project, nil)+limitDirectives, site)
 		}
+	}
 
-		config += fmt.Sprintf("}\n\n")
+	if proxied {
+		config += ng.productionRouting(project, production, canary, deployments)
+		config += ng.serverBlock(productionHost(projectName, ng.domain), false, ng.frontDoor(project, nil)+limitDirectives, productionProxy(project, canary))
 	}
 
 	// Write to file
@@ -110,23 +152,155 @@ func (ng *NginxGateway) WriteProjectConfig(projectID, projectName string, deploy
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
+	if ng.routing.Mode == RoutingPath {
+		return ng.writePathRoutes(project, deployments, production)
+	}
 	return nil
 }
 
-// RemoveProjectConfig deletes the configuration file for a project.
-// Silently succeeds if the file does not exist.
+// serverBlock renders the server of one hostname. With path routing the hostnames only listen on
+// loopback, behind the shared host's path router. Otherwise they listen publicly and, when loopback
+// is set, on 127.0.0.1 too, since nginx matches loopback connections only against servers listening there.
+func (ng *NginxGateway) serverBlock(serverName string, loopback bool, directives, body string) string {
+	config := "server {\n\n"
+	config += fmt.Sprintf("    server_name %s;\n", serverName)
+	switch {
+	case ng.routing.Mode == RoutingPath:
+		config += "    listen 127.0.0.1:80;\n"
+		config += "    absolute_redirect off;\n\n"
+	case loopback:
+		config += "    listen 80;\n"
+		config += "    listen 127.0.0.1:80;\n\n"
+	default:
+		config += "    listen 80;\n\n"
+	}
+	config += directives
+	config += body
+	config += "}\n\n"
+	return config
+}
+
+// frontDoor renders the maintenance, access and auth directives guarding the preview hostname of a
+// deployment, or the production hostname when dep is nil.
+func (ng *NginxGateway) frontDoor(project GatewayProject, dep *GatewayDeployment) string {
+	production := dep == nil
+	config := ""
+	if project.Maintenance != nil {
+		config += maintenanceServer(maintenanceVariable(project.ID), project.ID)
+	}
+
+	switch {
+	case ng.routing.Mode == RoutingPath:
+		config += trustLoopbackProxy()
+	case !production && project.DeploymentRouting && project.ProductionDeploymentID != "":
+		config += trustLoopbackProxy()
+	}
+
+	if production {
+		config += project.ProductionAccess.directives()
+	} else {
+		config += project.PreviewAccess.directives()
+	}
+
+	switch {
+	case !production && len(dep.Credentials) > 0:
+		config += authBasic(path.Join(nginxConfigDir, deploymentHtpasswd(project.ID, dep.ID)))
+	case project.protects(production):
+		config += authBasic(path.Join(nginxConfigDir, projectHtpasswd(project.ID)))
+	}
+	return config
+}
+
+// siteDirectives renders the root, headers, redirect rules, upstreams and file location serving a deployment.
+// Deployments proxying "/" have no files, so only their headers and upstreams are rendered.
+func (ng *NginxGateway) siteDirectives(project GatewayProject, dep GatewayDeployment, headerDirectives string) string {
+	if ProxiesSite(dep.Upstreams) {
+		return headerDirectives + upstreamLocations(dep.Upstreams, ng.resolver)
+	}
+
+	// Deployment directory
+	deploymentDir := fmt.Sprintf("/storage/deployments/%s/%s", dep.ProjectID, dep.ID)
+	st := siteFor(dep)
+	config := siteRoot(st, deploymentDir)
+	config += headerDirectives
+
+	// Redirect rules: the deployment's own first, then project-wide ones
+	redirects := append(append([]RedirectRule{}, dep.Redirects...), project.Redirects...)
+	if hasProxyRedirects(redirects) && ng.resolver != "" {
+		config += fmt.Sprintf("    resolver %s valid=30s;\n\n", ng.resolver)
+	}
+	config += redirectLocations(redirects)
+	config += upstreamLocations(dep.Upstreams, ng.resolver)
+
+	config += siteLocation(st)
+	return config
+}
+
+// variablePrefix builds a unique nginx variable prefix for a deployment or project.
+func variablePrefix(id string) string {
+	return "infario_" + strings.ReplaceAll(id, "-", "_")
+}
+
+// maintenanceVariable names the variable flagging clients that must see a project's maintenance page.
+func maintenanceVariable(projectID string) string {
+	return "$" + variablePrefix(projectID) + "_maintenance"
+}
+
+// siteRoot renders the server-level document root shared by the file and redirect locations.
+func siteRoot(st site, deploymentDir string) string {
+	rootPath := strings.TrimSuffix(deploymentDir+st.root, "/")
+
+	config := fmt.Sprintf("    root %s;\n", rootPath)
+	config += fmt.Sprintf("    index %s;\n\n", st.entryFile)
+	return config
+}
+
+// siteLocation renders the location block serving a deployment's files according to its serving mode.
+func siteLocation(st site) string {
+	config := ""
+	location := "/"
+	if st.prefix != "" {
+		// Only the entry directory's prefix serves files, as before serving modes existed
+		config += "    location / {\n        return 404;\n    }\n\n"
+		location = st.prefix + "/"
+	}
+	config += fmt.Sprintf("    location %s {\n", location)
+
+	switch st.mode {
+	case ServingModeSPA:
+		config += fmt.Sprintf("        try_files $uri $uri/ %s =404;\n", st.fallback())
+	case ServingModeCleanURLs:
+		config += "        try_files $uri $uri.html $uri/index.html $uri/ =404;\n"
+	default:
+		config += "        try_files $uri $uri/ =404;\n"
+	}
+
+	if page := st.errorPage(); page != "" {
+		config += fmt.Sprintf("        error_page 404 %s;\n", page)
+	}
+
+	config += "    }\n\n"
+	return config
+}
+
+// RemoveProjectConfig deletes the configuration, path routes, htpasswd and maintenance files for a project.
+// Silently succeeds if the files do not exist.
 func (ng *NginxGateway) RemoveProjectConfig(projectID string) error {
 	configPath := filepath.Join(ng.configDir, projectID+".conf")
 	err := os.Remove(configPath)
 
 	// Ignore "file not found" errors
-	if os.IsNotExist(err) {
-		return nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove config file: %w", err)
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to remove config file: %w", err)
+	routesPath := filepath.Join(ng.configDir, pathRoutesDir, projectID+".conf")
+	if err := os.Remove(routesPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove path routes file: %w", err)
 	}
 
-	return nil
+	if err := ng.writeHtpasswd(GatewayProject{ID: projectID}, nil); err != nil {
+		return err
+	}
+	return ng.writeMaintenancePage(GatewayProject{ID: projectID})
 }
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pathRoutesDir holds the per-project locations included by the shared host with path routing.
+// nginx only includes conf.d/*.conf at http level, so files in this directory are not loaded twice.
+const pathRoutesDir = "paths"
+
+// pathServer renders the server answering on the bare domain with path routing. It includes every
+// project's path routes and rejects anything else.
+func (ng *NginxGateway) pathServer() string {
+	config := "\nserver {\n\n"
+	config += fmt.Sprintf("    server_name %s;\n", ng.domain)
+	config += "    listen 80;\n\n"
+	config += fmt.Sprintf("    include %s/%s/*.conf;\n\n", nginxConfigDir, pathRoutesDir)
+	config += "    location / {\n"
+	config += "        return 404;\n"
+	config += "    }\n\n"
+	config += "}\n"
+	return config
+}
+
+// writePathRoutes writes the locations routing a project's path prefixes to its loopback servers.
+// Each prefix is proxied to the hostname it replaces, so per-hostname settings apply unchanged.
+func (ng *NginxGateway) writePathRoutes(project GatewayProject, deployments []GatewayDeployment, production *GatewayDeployment) error {
+	dir := filepath.Join(ng.configDir, pathRoutesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create path routes directory: %w", err)
+	}
+
+	config := fmt.Sprintf("# Auto-generated path routes for project: %s\n\n", project.Name)
+	for _, dep := range deployments {
+		config += pathRoute(previewPrefix(dep.PreviewID, project.Name), previewHost(dep.PreviewID, project.Name, ng.domain))
+	}
+	if production != nil {
+		config += pathRoute(productionPrefix(project.Name), productionHost(project.Name, ng.domain))
+	}
+
+	routesPath := filepath.Join(dir, project.ID+".conf")
+	if err := os.WriteFile(routesPath, []byte(config), 0644); err != nil {
+		return fmt.Errorf("failed to write path routes file: %w", err)
+	}
+
+	return nil
+}
+
+// pathRoute renders the location proxying a path prefix to a hostname's loopback server. The prefix is
+// stripped from requests and added back to redirects and cookie paths in responses. Longer prefixes
+// win, so /{project}/{preview_id}/ takes precedence over the production prefix /{project}/.
+func pathRoute(prefix, host string) string {
+	trimmed := strings.TrimSuffix(prefix, "/")
+
+	config := fmt.Sprintf("location = %s {\n", trimmed)
+	config += fmt.Sprintf("    return 301 %s$is_args$args;\n", prefix)
+	config += "}\n\n"
+
+	config += fmt.Sprintf("location ^~ %s {\n", prefix)
+	config += "    proxy_pass http://127.0.0.1:80/;\n"
+	config += "    proxy_http_version 1.1;\n"
+	config += fmt.Sprintf("    proxy_set_header Host %s;\n", host)
+	config += "    proxy_set_header X-Real-IP $remote_addr;\n"
+	config += "    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n"
+	config += "    proxy_set_header X-Forwarded-Proto $scheme;\n"
+	config += "    proxy_set_header X-Forwarded-Host $host;\n"
+	config += fmt.Sprintf("    proxy_set_header X-Forwarded-Prefix %s;\n", trimmed)
+	config += "    proxy_set_header Upgrade $http_upgrade;\n"
+	config += "    proxy_set_header Connection $connection_upgrade;\n"
+	config += fmt.Sprintf("    proxy_redirect / %s;\n", prefix)
+	config += fmt.Sprintf("    proxy_cookie_path / %s;\n", prefix)
+	config += "    proxy_send_timeout 3600s;\n"
+	config += "    proxy_read_timeout 3600s;\n"
+	config += "}\n\n"
+	return config
+}
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// deploymentCookie pins a client to a deployment on the production hostname.
+	// The X-Infario-Deployment request header does the same for a single request and takes precedence.
+	deploymentCookie = "infario_deployment"
+	// previewLinkPath sets the deployment cookie from a shareable link, /__infario/preview?d={preview_id}.
+	// Deployments may also be named by hash. An unknown or empty value clears it.
+	previewLinkPath = "/__infario/preview"
+)
+
+// routableHash limits the deployments clients can pin to hashes that are safe inside nginx map keys.
+var routableHash = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// productionTargets returns the ready deployments served on the production hostname.
+// The canary is only returned while both sides of the split are ready.
+func productionTargets(project GatewayProject, deployments []GatewayDeployment) (production, canary *GatewayDeployment) {
+	for i := range deployments {
+		if deployments[i].ID == project.ProductionDeploymentID {
+			production = &deployments[i]
+		}
+		if project.Canary != nil && deployments[i].ID == project.Canary.DeploymentID {
+			canary = &deployments[i]
+		}
+	}
+	if production == nil || canary == nil || production.ID == canary.ID {
+		return production, nil
+	}
+	return production, canary
+}
+
+// productionProxied reports whether the production hostname picks its deployment per request,
+// proxying to loopback servers instead of serving files itself.
+func productionProxied(project GatewayProject, production, canary *GatewayDeployment) bool {
+	return production != nil && (canary != nil || project.DeploymentRouting)
+}
+
+// internalHost names the loopback-only server of a deployment behind the production proxy.
+func internalHost(deploymentID string) string {
+	return deploymentID + ".internal"
+}
+
+// internalServerBlock renders a deployment on a loopback-only server for the production proxy.
+// Redirects stay relative so the internal hostname never reaches clients.
+func internalServerBlock(deploymentID, site string) string {
+	config := "server {\n\n"
+	config += fmt.Sprintf("    server_name %s;\n", internalHost(deploymentID))
+	config += "    listen 127.0.0.1:80;\n"
+	config += "    absolute_redirect off;\n\n"
+	config += site
+	config += "}\n\n"
+	return config
+}
+
+// upstreamVariable names the variable holding the hostname a production request is proxied to.
+func upstreamVariable(projectID string) string {
+	return "$" + variablePrefix(projectID) + "_upstream"
+}
+
+// previewCookieVariable names the variable holding the Set-Cookie value of the preview link.
+func previewCookieVariable(projectID string) string {
+	return "$" + variablePrefix(projectID) + "_preview_cookie"
+}
+
+// productionRouting renders the http-level blocks choosing the upstream of each production request.
+// A request pinned to a deployment goes to its preview hostname, so preview protection still applies;
+// anything else goes to the canary split or the production deployment.
+func (ng *NginxGateway) productionRouting(project GatewayProject, production, canary *GatewayDeployment, deployments []GatewayDeployment) string {
+	upstream := upstreamVariable(project.ID)
+	if !project.DeploymentRouting {
+		return canarySplit(project, production.ID, canary.ID, upstream)
+	}
+
+	config := ""
+	fallback := internalHost(production.ID)
+	if canary != nil {
+		fallback = "$" + variablePrefix(project.ID) + "_split"
+		config += canarySplit(project, production.ID, canary.ID, fallback)
+	}
+
+	requested := "$" + variablePrefix(project.ID) + "_requested"
+	config += fmt.Sprintf("map $http_x_infario_deployment %s {\n", requested)
+	config += fmt.Sprintf("    \"\" $cookie_%s;\n", deploymentCookie)
+	config += "    default $http_x_infario_deployment;\n"
+	config += "}\n\n"
+
+	config += fmt.Sprintf("map %s %s {\n", requested, upstream)
+	config += fmt.Sprintf("    default %s;\n", fallback)
+	for _, dep := range deployments {
+		for _, key := range routingKeys(dep) {
+			config += fmt.Sprintf("    \"~*^%s$\" %s;\n", regexp.QuoteMeta(key), previewHost(dep.PreviewID, project.Name, ng.domain))
+		}
+	}
+	config += "}\n\n"
+
+	config += fmt.Sprintf("map $arg_d %s {\n", previewCookieVariable(project.ID))
+	config += fmt.Sprintf("    default \"%s=; Path=/; Max-Age=0; HttpOnly; SameSite=Lax\";\n", deploymentCookie)
+	for _, dep := range deployments {
+		for _, key := range routingKeys(dep) {
+			config += fmt.Sprintf("    \"~*^%s$\" \"%s=%s; Path=/; HttpOnly; SameSite=Lax\";\n", regexp.QuoteMeta(key), deploymentCookie, dep.PreviewID)
+		}
+	}
+	config += "}\n\n"
+	return config
+}
+
+// routingKeys lists the names clients may pin a deployment by: its preview ID and, when it is safe
+// inside nginx map keys and differs, its hash.
+func routingKeys(dep GatewayDeployment) []string {
+	keys := []string{dep.PreviewID}
+	if routableHash.MatchString(dep.Hash) && !strings.EqualFold(dep.Hash, dep.PreviewID) {
+		keys = append(keys, dep.Hash)
+	}
+	return keys
+}
+
+// productionProxy renders the production server body proxying each request to its chosen upstream.
+func productionProxy(project GatewayProject, canary *GatewayDeployment) string {
+	config := ""
+	if canary != nil {
+		config += canaryCookieHeader(project)
+	}
+
+	if project.DeploymentRouting {
+		config += fmt.Sprintf("    location = %s {\n", previewLinkPath)
+		config += fmt.Sprintf("        add_header Set-Cookie %s always;\n", previewCookieVariable(project.ID))
+		config += "        add_header Cache-Control \"no-store\" always;\n"
+		config += "        return 302 /;\n"
+		config += "    }\n\n"
+	}
+
+	config += "    location / {\n"
+	config += "        proxy_pass http://127.0.0.1:80;\n"
+	config += fmt.Sprintf("        proxy_set_header Host %s;\n", upstreamVariable(project.ID))
+	config += "        proxy_set_header X-Real-IP $remote_addr;\n"
+	config += "        proxy_set_header X-Forwarded-Host $host;\n"
+	config += "        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n"
+	config += "    }\n\n"
+	return config
+}
+
+// trustLoopbackProxy renders the directives restoring the client address of requests forwarded over
+// loopback, by the production proxy or the path router, so access rules and limits see the real client.
+func trustLoopbackProxy() string {
+	config := "    set_real_ip_from 127.0.0.1;\n"
+	config += "    real_ip_header X-Real-IP;\n\n"
+	return config
+}
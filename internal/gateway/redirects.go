@@ -0,0 +1,269 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RedirectRule maps a source path pattern to a destination, following Netlify's _redirects semantics.
+// Sources may contain :placeholders matching a single segment and a trailing * splat;
+// destinations may reference them as :name and :splat. Unless forced, a rule only applies when
+// no file exists at the requested path.
+// @Description Redirect, rewrite or proxy rule
+// @Name RedirectRule
+type RedirectRule struct {
+	From   string `json:"from" validate:"required"`
+	To     string `json:"to" validate:"required"`
+	Status int    `json:"status,omitempty" validate:"omitempty,oneof=200 301 302 303 307 308 404 410"`
+	Force  bool   `json:"force,omitempty"` // Apply even when a file exists at the path ("301!" in _redirects)
+}
+
+var (
+	// pathPatternChars limits path patterns to URL path characters that are safe inside a quoted nginx regex.
+	pathPatternChars = regexp.MustCompile(`^/[A-Za-z0-9._~!()+,=@%/:*-]*$`)
+	// redirectTargetChars rejects whitespace, quotes and nginx syntax in destinations.
+	redirectTargetChars = regexp.MustCompile(`^[^\s"'\\;{}$#]+$`)
+	placeholderPattern  = regexp.MustCompile(`:[A-Za-z_][A-Za-z0-9_]*`)
+	placeholderSegment  = regexp.MustCompile(`^:[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ParseRedirects reads rules in the _redirects file format: one "from to [status][!]" rule per line,
+// with blank lines and # comments ignored. Errors report the offending line number.
+func ParseRedirects(r io.Reader) ([]RedirectRule, error) {
+	var rules []RedirectRule
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected source and destination", line)
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: query, country and language conditions are not supported", line)
+		}
+
+		rule := RedirectRule{From: fields[0], To: fields[1], Status: 301}
+		if len(fields) == 3 {
+			status, err := strconv.Atoi(strings.TrimSuffix(fields[2], "!"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid status %q", line, fields[2])
+			}
+			rule.Status = status
+			rule.Force = strings.HasSuffix(fields[2], "!")
+		}
+
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read redirects: %w", err)
+	}
+
+	return rules, nil
+}
+
+// Validate checks that a rule can be compiled into nginx configuration safely.
+func (r RedirectRule) Validate() error {
+	if err := validatePathPattern(r.From); err != nil {
+		return fmt.Errorf("source %w", err)
+	}
+
+	if !redirectTargetChars.MatchString(r.To) {
+		return fmt.Errorf("destination %q must not contain spaces, quotes, $, ; or braces", r.To)
+	}
+
+	external := r.isExternal()
+	if !external && !strings.HasPrefix(r.To, "/") {
+		return fmt.Errorf("destination %q must be a path or an http(s) URL", r.To)
+	}
+
+	switch r.status() {
+	case 200:
+	case 301, 302, 303, 307, 308, 410:
+	case 404:
+		if external || placeholderPattern.MatchString(r.To) {
+			return fmt.Errorf("404 rules must point to a fixed page inside the deployment")
+		}
+	default:
+		return fmt.Errorf("unsupported status %d", r.Status)
+	}
+
+	// Only whole segments are captured; a colon inside a segment, as in /post-:id, is literal
+	defined := map[string]bool{}
+	for _, segment := range strings.Split(r.From, "/") {
+		if placeholderSegment.MatchString(segment) {
+			defined[segment] = true
+		}
+	}
+	if strings.HasSuffix(r.From, "*") {
+		defined[":splat"] = true
+	}
+	for _, name := range placeholderPattern.FindAllString(r.To, -1) {
+		if !defined[name] {
+			return fmt.Errorf("destination uses %s which is not a whole segment of the source", name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateRedirects validates every rule, reporting the index of the first invalid one.
+func ValidateRedirects(rules []RedirectRule) error {
+	for i, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// status returns the effective status code, defaulting to a permanent redirect.
+func (r RedirectRule) status() int {
+	if r.Status == 0 {
+		return 301
+	}
+	return r.Status
+}
+
+// isExternal reports whether the destination is an absolute http(s) URL.
+func (r RedirectRule) isExternal() bool {
+	return strings.HasPrefix(r.To, "http://") || strings.HasPrefix(r.To, "https://")
+}
+
+// pattern compiles the source into an anchored regex with named captures for placeholders and the splat.
+func (r RedirectRule) pattern() string {
+	return pathPattern(r.From, true) + "$"
+}
+
+// validatePathPattern checks a source path pattern shared by redirect and header rules.
+func validatePathPattern(pattern string) error {
+	if !pathPatternChars.MatchString(pattern) {
+		return fmt.Errorf("%q must be a path starting with / without spaces, quotes or $", pattern)
+	}
+	if i := strings.Index(pattern, "*"); i >= 0 && (i != len(pattern)-1 || !strings.HasSuffix(pattern, "/*")) {
+		return fmt.Errorf("%q may only use * as its last segment", pattern)
+	}
+	return nil
+}
+
+// pathPattern compiles a path pattern into an unterminated regex starting with ^. Placeholders match
+// a single segment and a trailing * matches the rest of the path; with captures set they are exposed
+// as $rd_{name} and $rd_splat.
+func pathPattern(source string, captures bool) string {
+	source = strings.TrimSuffix(source, "/")
+	splat := strings.HasSuffix(source, "*")
+	source = strings.TrimSuffix(strings.TrimSuffix(source, "*"), "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, segment := range strings.Split(source, "/")[1:] {
+		b.WriteString("/")
+		switch {
+		case placeholderSegment.MatchString(segment) && captures:
+			fmt.Fprintf(&b, "(?<rd_%s>[^/]+)", segment[1:])
+		case placeholderSegment.MatchString(segment):
+			b.WriteString("[^/]+")
+		default:
+			b.WriteString(regexp.QuoteMeta(segment))
+		}
+	}
+
+	switch {
+	case splat && captures:
+		b.WriteString("(?:/(?<rd_splat>.*))?")
+	case splat:
+		b.WriteString("(?:/.*)?")
+	default:
+		b.WriteString("/?")
+	}
+	return b.String()
+}
+
+// target rewrites :placeholders in the destination into the nginx capture variables of pattern.
+func (r RedirectRule) target() string {
+	return placeholderPattern.ReplaceAllStringFunc(r.To, func(name string) string {
+		return "${rd_" + name[1:] + "}"
+	})
+}
+
+// redirectLocations renders rules as regex locations. Regex locations are matched in order and win
+// over the prefix location serving files, so earlier rules take precedence. Rules that are not forced
+// first try the file at the requested path and only fall through to the rule when it is missing.
+func redirectLocations(rules []RedirectRule) string {
+	config := ""
+	for i, rule := range rules {
+		pattern := rule.pattern()
+
+		config += fmt.Sprintf("    location ~ \"%s\" {\n", pattern)
+		if rule.Force {
+			config += rule.action()
+			config += "    }\n\n"
+			continue
+		}
+
+		named := fmt.Sprintf("@redirect_%d", i)
+		config += fmt.Sprintf("        try_files $uri $uri/ %s;\n", named)
+		config += "    }\n\n"
+		config += fmt.Sprintf("    location %s {\n", named)
+		config += rule.action()
+		config += "    }\n\n"
+	}
+	return config
+}
+
+// action renders the directives applying a rule. The pattern is matched again so its captures
+// are available no matter how the request reached the location.
+func (r RedirectRule) action() string {
+	pattern := r.pattern()
+	target := r.target()
+
+	args := "$is_args$args"
+	if strings.Contains(target, "?") {
+		args = ""
+	}
+
+	switch status := r.status(); {
+	case status == 200 && r.isExternal():
+		config := "        proxy_set_header Host $proxy_host;\n"
+		config += "        proxy_ssl_server_name on;\n"
+		config += fmt.Sprintf("        if ($uri ~ \"%s\") {\n", pattern)
+		config += fmt.Sprintf("            proxy_pass %s%s;\n", target, args)
+		config += "        }\n"
+		return config
+	case status == 200:
+		return fmt.Sprintf("        rewrite \"%s\" %s last;\n", pattern, target)
+	case status == 404:
+		return fmt.Sprintf("        error_page 404 %s;\n        return 404;\n", r.To)
+	case status == 410:
+		return "        return 410;\n"
+	default:
+		config := fmt.Sprintf("        if ($uri ~ \"%s\") {\n", pattern)
+		config += fmt.Sprintf("            return %d %s%s;\n", status, target, args)
+		config += "        }\n"
+		return config
+	}
+}
+
+// hasProxyRedirects reports whether any rule proxies to an external URL, which requires a resolver.
+func hasProxyRedirects(rules []RedirectRule) bool {
+	for _, rule := range rules {
+		if rule.status() == 200 && rule.isExternal() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Routing modes decide how deployments are addressed on the gateway's domain.
+const (
+	// RoutingSubdomain serves deployments on {preview_id}.{project}.{domain} and {project}.{domain}.
+	// It requires wildcard DNS for the domain.
+	RoutingSubdomain = "subdomain"
+	// RoutingPath serves deployments on the domain itself under path prefixes: /{project}/{preview_id}/
+	// and /{project}/ for production.
+	RoutingPath = "path"
+)
+
+// Routing builds the public URLs of deployments for a routing mode and domain, using the same
+// hostnames and prefixes the gateways serve.
+type Routing struct {
+	Mode   string
+	Domain string
+	Scheme string // "http", or "https" once TLS terminates in front of the gateway
+}
+
+// NewRouting returns the routing for a mode and scheme, falling back to subdomain routing and http
+// for unknown values.
+func NewRouting(mode, domain, scheme string) Routing {
+	if mode != RoutingPath {
+		mode = RoutingSubdomain
+	}
+	if scheme != "https" {
+		scheme = "http"
+	}
+	return Routing{Mode: mode, Domain: domain, Scheme: scheme}
+}
+
+// PreviewURL returns the public URL of a deployment.
+func (r Routing) PreviewURL(previewID, projectName string) string {
+	if r.Mode == RoutingPath {
+		return fmt.Sprintf("%s://%s%s", r.Scheme, r.Domain, previewPrefix(previewID, projectName))
+	}
+	return fmt.Sprintf("%s://%s/", r.Scheme, previewHost(previewID, projectName, r.Domain))
+}
+
+// ProductionURL returns the public URL of a project's production deployment.
+func (r Routing) ProductionURL(projectName string) string {
+	if r.Mode == RoutingPath {
+		return fmt.Sprintf("%s://%s%s", r.Scheme, r.Domain, productionPrefix(projectName))
+	}
+	return fmt.Sprintf("%s://%s/", r.Scheme, productionHost(projectName, r.Domain))
+}
+
+// previewPrefix builds the path a deployment is served under with path routing: /{project}/{preview_id}/.
+func previewPrefix(previewID, projectName string) string {
+	return fmt.Sprintf("/%s/%s/", projectName, previewID)
+}
+
+// productionPrefix builds the path a project's production deployment is served under with path routing.
+func productionPrefix(projectName string) string {
+	return fmt.Sprintf("/%s/", projectName)
+}
+
+// previewHost builds the hostname a deployment is served on: {preview_id}.{project}.{domain}.
+func previewHost(previewID, projectName, domain string) string {
+	return fmt.Sprintf("%s.%s.%s", previewID, projectName, domain)
+}
+
+// productionHost builds the hostname a project's production deployment is served on: {project}.{domain}.
+func productionHost(projectName, domain string) string {
+	return fmt.Sprintf("%s.%s", projectName, domain)
+}
+
+// parseHost reverses previewHost, splitting a request host into deployment preview ID and project name.
+// The port, if any, is ignored and matching is case-insensitive.
+func parseHost(host, domain string) (previewID, projectName string, ok bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	suffix := "." + strings.ToLower(domain)
+	if !strings.HasSuffix(host, suffix) {
+		return "", "", false
+	}
+
+	labels := strings.TrimSuffix(host, suffix)
+	previewID, projectName, found := strings.Cut(labels, ".")
+	if !found || previewID == "" || projectName == "" || strings.Contains(projectName, ".") {
+		return "", "", false
+	}
+
+	return previewID, projectName, true
+}
+
+// parseProductionHost reverses productionHost, returning the project name of a request host. The port,
+// if any, is ignored and matching is case-insensitive.
+func parseProductionHost(host, domain string) (projectName string, ok bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	projectName, found := strings.CutSuffix(host, "."+strings.ToLower(domain))
+	if !found || projectName == "" || strings.Contains(projectName, ".") {
+		return "", false
+	}
+	return projectName, true
+}
+
+// parsePath reverses previewPrefix, splitting a request path into deployment preview ID, project name and
+// the path below the prefix, which always starts with "/".
+func parsePath(requestPath string) (previewID, projectName, rest string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(requestPath, "/"), "/", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
+	}
+	return parts[1], strings.ToLower(parts[0]), "/" + parts[2], true
+}
+
+// parseProductionPath reverses productionPrefix, splitting a request path into project name and the path
+// below the prefix, which always starts with "/".
+func parseProductionPath(requestPath string) (projectName, rest string, ok bool) {
+	projectName, rest, found := strings.Cut(strings.TrimPrefix(requestPath, "/"), "/")
+	if !found || projectName == "" {
+		return "", "", false
+	}
+	return strings.ToLower(projectName), "/" + rest, true
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"path"
+	"strings"
+)
+
+// Serving modes decide what happens when a request does not match a file.
+const (
+	// ServingModeSPA falls back to the entry file so client-side routers can handle the path.
+	ServingModeSPA = "spa"
+	// ServingModeStatic serves files as-is and a custom 404.html when present.
+	ServingModeStatic = "static"
+	// ServingModeCleanURLs resolves /about to /about.html or /about/index.html before giving up.
+	ServingModeCleanURLs = "clean_urls"
+)
+
+// notFoundPage is the custom error page looked up in static and clean URLs modes.
+const notFoundPage = "/404.html"
+
+// site describes how a deployment's files map onto URLs.
+type site struct {
+	root      string // document root relative to the deployment directory, always starting with "/"
+	entryFile string // served for directory requests and as the SPA fallback
+	mode      string
+	prefix    string // URL prefix files are served under, e.g. "/dist"; empty serves every path
+}
+
+// siteFor derives the document root, entry file and serving mode of a deployment.
+// Entry paths ending with "/" are directories served with index.html; anything else is an entry file
+// served from its parent directory. Deployments processed before serving modes existed carry no mode
+// and no trailing slash, so the mode and entry kind are inferred from the file extension instead, and
+// their entry directories keep being served under their own URL prefix from the deployment root.
+func siteFor(dep GatewayDeployment) site {
+	entryPath := "/"
+	if dep.EntryPath != nil && *dep.EntryPath != "" {
+		entryPath = *dep.EntryPath
+	}
+
+	mode := dep.ServingMode
+	isDir := strings.HasSuffix(entryPath, "/")
+	if mode == "" {
+		isDir = isDir || !strings.Contains(path.Base(entryPath), ".")
+		mode = ServingModeStatic
+		if !isDir {
+			mode = ServingModeSPA
+		}
+		if prefix := path.Clean("/" + entryPath); isDir && prefix != "/" {
+			return site{root: "/", entryFile: "index.html", mode: mode, prefix: prefix}
+		}
+	}
+
+	if isDir {
+		return site{root: path.Clean("/" + entryPath), entryFile: "index.html", mode: mode}
+	}
+
+	return site{
+		root:      path.Dir(path.Clean("/" + entryPath)),
+		entryFile: path.Base(entryPath),
+		mode:      mode,
+	}
+}
+
+// candidates lists the files, relative to the document root, tried in order for a request path.
+func (s site) candidates(urlPath string) []string {
+	urlPath = path.Clean("/" + urlPath)
+	if s.prefix != "" && urlPath != s.prefix && !strings.HasPrefix(urlPath, s.prefix+"/") {
+		return nil
+	}
+	index := path.Join(urlPath, s.entryFile)
+
+	switch s.mode {
+	case ServingModeCleanURLs:
+		if urlPath == "/" {
+			return []string{index}
+		}
+		return []string{urlPath, urlPath + ".html", path.Join(urlPath, "index.html")}
+	default:
+		return []string{urlPath, index}
+	}
+}
+
+// fallback returns the file served with status 200 when no candidate matched (SPA mode only).
+func (s site) fallback() string {
+	if s.mode == ServingModeSPA {
+		return "/" + s.entryFile
+	}
+	return ""
+}
+
+// errorPage returns the file served with status 404 when no candidate matched.
+func (s site) errorPage() string {
+	if s.mode == ServingModeSPA || s.prefix != "" {
+		return ""
+	}
+	return notFoundPage
+}
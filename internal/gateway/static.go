@@ -0,0 +1,505 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeploymentResolver looks up what the static gateway serves. Each method returns nil when nothing matches.
+type DeploymentResolver interface {
+	// ResolveDeployment returns the ready deployment served for a project name and preview ID.
+	ResolveDeployment(ctx context.Context, projectName, previewID string) (*GatewayDeployment, error)
+	// ResolvePinned returns the ready deployment a client pinned by preview ID or hash on the production hostname.
+	ResolvePinned(ctx context.Context, projectName, key string) (*GatewayDeployment, error)
+	// ResolveProduction returns a project with its ready production and canary deployments.
+	ResolveProduction(ctx context.Context, projectName string) (*GatewayProject, []GatewayDeployment, error)
+}
+
+// precompressedVariants lists the encodings served from sibling files, in order of preference.
+var precompressedVariants = []struct {
+	encoding  string
+	extension string
+}{
+	{encoding: "br", extension: ".br"},
+	{encoding: "gzip", extension: ".gz"},
+}
+
+// StaticGateway serves deployment files straight from storage, as an alternative to nginx.
+// Requests are resolved with the same hostnames or path prefixes as NginxGateway, including the production
+// hostname with its canary split and deployment pinning.
+type StaticGateway struct {
+	routing  Routing
+	resolver DeploymentResolver
+	storage  fs.FS
+	logger   *slog.Logger
+
+	credentials *credentialCache
+
+	mu         sync.Mutex
+	transports map[[2]int]*http.Transport // Keyed by upstream connect and read timeouts
+}
+
+// NewStaticGateway creates a new static gateway.
+// storage must be rooted at the storage base directory (the parent of "deployments").
+func NewStaticGateway(routing Routing, resolver DeploymentResolver, storage fs.FS, logger *slog.Logger) *StaticGateway {
+	return &StaticGateway{
+		routing:     routing,
+		resolver:    resolver,
+		storage:     storage,
+		logger:      logger,
+		credentials: newCredentialCache(),
+		transports:  map[[2]int]*http.Transport{},
+	}
+}
+
+// ServeHTTP resolves the request host, or path prefix with path routing, to a deployment and serves it.
+func (g *StaticGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.routing.Mode == RoutingPath {
+		g.servePath(w, r)
+		return
+	}
+
+	if previewID, projectName, ok := parseHost(r.Host, g.routing.Domain); ok {
+		if !g.servePreview(w, r, projectName, previewID) {
+			http.NotFound(w, r)
+		}
+		return
+	}
+	if projectName, ok := parseProductionHost(r.Host, g.routing.Domain); ok {
+		g.serveProduction(w, r, projectName, "/")
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// servePath serves a request with path routing. A deployment's /{project}/{preview_id}/ prefix takes
+// precedence over the production prefix /{project}/, as the longer nginx location does.
+func (g *StaticGateway) servePath(w http.ResponseWriter, r *http.Request) {
+	if previewID, projectName, rest, ok := parsePath(r.URL.Path); ok {
+		if g.servePreview(w, stripPath(r, rest), projectName, previewID) {
+			return
+		}
+	}
+	if projectName, rest, ok := parseProductionPath(r.URL.Path); ok {
+		g.serveProduction(w, stripPath(r, rest), projectName, productionPrefix(projectName))
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// servePreview serves a deployment on its preview hostname. It reports false, without responding,
+// when no ready deployment matches.
+func (g *StaticGateway) servePreview(w http.ResponseWriter, r *http.Request, projectName, previewID string) bool {
+	dep, err := g.resolver.ResolveDeployment(r.Context(), projectName, previewID)
+	if err != nil {
+		g.resolveFailed(w, r, projectName, err)
+		return true
+	}
+	if dep == nil {
+		return false
+	}
+
+	g.serveGuarded(w, r, dep)
+	return true
+}
+
+// serveProduction serves a project's production hostname: the deployment a client pinned, when the project
+// allows it, or else its side of the canary split or the production deployment. base is the path the
+// hostname is served under, where cookies and the preview link redirect are scoped.
+func (g *StaticGateway) serveProduction(w http.ResponseWriter, r *http.Request, projectName, base string) {
+	project, deployments, err := g.resolver.ResolveProduction(r.Context(), projectName)
+	if err != nil {
+		g.resolveFailed(w, r, projectName, err)
+		return
+	}
+	if project == nil {
+		http.NotFound(w, r)
+		return
+	}
+	production, canary := productionTargets(*project, deployments)
+	if production == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if project.Maintenance != nil && !containsIP(project.Maintenance.BypassIPs, remoteIP(r)) {
+		g.serveMaintenance(w, r, project.Maintenance)
+		return
+	}
+	if !project.ProductionAccess.permits(remoteIP(r)) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if project.protects(true) && !g.credentials.authorized(r, project.Credentials) {
+		g.requireCredentials(w)
+		return
+	}
+
+	if project.DeploymentRouting {
+		if r.URL.Path == previewLinkPath {
+			g.servePreviewLink(w, r, projectName, base)
+			return
+		}
+		if key := pinnedDeployment(r); key != "" {
+			pinned, err := g.resolver.ResolvePinned(r.Context(), projectName, key)
+			if err != nil {
+				g.resolveFailed(w, r, projectName, err)
+				return
+			}
+			if pinned != nil {
+				g.serveGuarded(w, r, pinned)
+				return
+			}
+		}
+	}
+
+	dep := production
+	if canary != nil {
+		dep = canarySide(w, r, *project.Canary, production, canary, base)
+	}
+	g.serveDeployment(w, r, dep)
+}
+
+// servePreviewLink pins the client to the deployment named by the link's d parameter, or unpins it when
+// no ready deployment matches, then sends it to the production hostname.
+func (g *StaticGateway) servePreviewLink(w http.ResponseWriter, r *http.Request, projectName, base string) {
+	cookie := &http.Cookie{Name: deploymentCookie, Path: base, MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode}
+	if key := r.URL.Query().Get("d"); key != "" {
+		dep, err := g.resolver.ResolvePinned(r.Context(), projectName, key)
+		if err != nil {
+			g.resolveFailed(w, r, projectName, err)
+			return
+		}
+		if dep != nil {
+			cookie.Value, cookie.MaxAge = dep.PreviewID, 0
+		}
+	}
+
+	http.SetCookie(w, cookie)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, base, http.StatusFound)
+}
+
+// pinnedDeployment returns the deployment a request asks for on the production hostname: the
+// X-Infario-Deployment header, or else the deployment cookie.
+func pinnedDeployment(r *http.Request) string {
+	if key := r.Header.Get("X-Infario-Deployment"); key != "" {
+		return key
+	}
+	if cookie, err := r.Cookie(deploymentCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// serveGuarded serves a deployment behind the maintenance mode, access rules and credentials of its
+// preview hostname.
+func (g *StaticGateway) serveGuarded(w http.ResponseWriter, r *http.Request, dep *GatewayDeployment) {
+	if dep.Maintenance != nil && !containsIP(dep.Maintenance.BypassIPs, remoteIP(r)) {
+		g.serveMaintenance(w, r, dep.Maintenance)
+		return
+	}
+
+	if !dep.Access.permits(remoteIP(r)) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if len(dep.Credentials) > 0 && !g.credentials.authorized(r, dep.Credentials) {
+		g.requireCredentials(w)
+		return
+	}
+
+	g.serveDeployment(w, r, dep)
+}
+
+// serveDeployment serves the requested file of a deployment. Requests under an upstream's path are
+// proxied; anything else must be a GET or HEAD.
+func (g *StaticGateway) serveDeployment(w http.ResponseWriter, r *http.Request, dep *GatewayDeployment) {
+	if upstream := upstreamFor(dep.Upstreams, r.URL.Path); upstream != nil {
+		g.serveUpstream(w, r, upstream)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	st := siteFor(*dep)
+	root := path.Join("deployments", dep.ProjectID, dep.ID, st.root)
+
+	name, ok := g.lookup(root, st, r.URL.Path)
+	if !ok {
+		g.serveNotFound(w, r, root, st)
+		return
+	}
+
+	if err := g.serveFile(w, r, name); err != nil {
+		if g.logger != nil {
+			g.logger.ErrorContext(r.Context(), "failed to serve file", "deployment_id", dep.ID, "file", name, "error", err)
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// requireCredentials asks the client for basic auth credentials.
+func (g *StaticGateway) requireCredentials(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s", charset="UTF-8"`, authRealm))
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// resolveFailed responds to a request whose deployment could not be looked up.
+func (g *StaticGateway) resolveFailed(w http.ResponseWriter, r *http.Request, projectName string, err error) {
+	if g.logger != nil {
+		g.logger.ErrorContext(r.Context(), "failed to resolve deployment", "project", projectName, "host", r.Host, "path", r.URL.Path, "error", err)
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// stripPath returns a shallow copy of a request with its URL path replaced by the path below a routing
+// prefix, like http.StripPrefix, so paths are relative to the deployment.
+func stripPath(r *http.Request, rest string) *http.Request {
+	routed := new(http.Request)
+	*routed = *r
+	routed.URL = new(url.URL)
+	*routed.URL = *r.URL
+	routed.URL.Path = rest
+	routed.URL.RawPath = ""
+	return routed
+}
+
+// lookup maps a request path to a file in storage following the deployment's serving mode,
+// including the SPA fallback to the entry file.
+func (g *StaticGateway) lookup(root string, st site, requestPath string) (string, bool) {
+	for _, candidate := range st.candidates(requestPath) {
+		if name, ok := g.regularFile(root, candidate); ok {
+			return name, true
+		}
+	}
+
+	if fallback := st.fallback(); fallback != "" {
+		return g.regularFile(root, fallback)
+	}
+
+	return "", false
+}
+
+// regularFile returns the storage name of a file under root if it exists and is not a directory.
+func (g *StaticGateway) regularFile(root, file string) (string, bool) {
+	name := path.Join(root, file)
+	info, err := fs.Stat(g.storage, name)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return name, true
+}
+
+// serveNotFound responds with the deployment's custom 404 page when its serving mode has one.
+func (g *StaticGateway) serveNotFound(w http.ResponseWriter, r *http.Request, root string, st site) {
+	page := st.errorPage()
+	if page == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	name, ok := g.regularFile(root, page)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := fs.ReadFile(g.storage, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
+
+// serveMaintenance responds with a project's maintenance page and a 503 status.
+func (g *StaticGateway) serveMaintenance(w http.ResponseWriter, r *http.Request, m *Maintenance) {
+	page := m.Page
+	if page == "" {
+		page = defaultMaintenancePage
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", "300")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if r.Method != http.MethodHead {
+		io.WriteString(w, page)
+	}
+}
+
+// serveUpstream proxies a request to an upstream with the same headers and timeouts nginx applies.
+// Websocket upgrades are handled by the reverse proxy itself.
+func (g *StaticGateway) serveUpstream(w http.ResponseWriter, r *http.Request, upstream *Upstream) {
+	target, err := url.Parse(upstream.URL)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = target.Scheme
+			pr.Out.URL.Host = target.Host
+			if target.Path != "" {
+				pr.Out.URL.Path = target.Path + strings.TrimPrefix(pr.In.URL.Path, upstream.Path)
+				pr.Out.URL.RawPath = ""
+			}
+
+			pr.Out.Host = target.Host
+			if upstream.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			pr.SetXForwarded()
+			if ip := remoteIP(pr.In); ip != nil {
+				pr.Out.Header.Set("X-Real-IP", ip.String())
+			}
+			for name, value := range upstream.Headers {
+				pr.Out.Header.Set(name, value)
+			}
+		},
+		Transport: g.transport(upstream),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if g.logger != nil {
+				g.logger.ErrorContext(r.Context(), "failed to proxy request", "upstream", upstream.URL, "error", err)
+			}
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// transport returns a shared transport for an upstream's timeouts so connections are reused across requests.
+func (g *StaticGateway) transport(upstream *Upstream) http.RoundTripper {
+	connectTimeout, readTimeout := upstream.timeouts()
+	key := [2]int{connectTimeout, readTimeout}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if t, ok := g.transports[key]; ok {
+		return t
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: time.Duration(connectTimeout) * time.Second}).DialContext
+	t.ResponseHeaderTimeout = time.Duration(readTimeout) * time.Second
+	g.transports[key] = t
+	return t
+}
+
+// serveFile writes a file with ETag, range and precompressed variant support.
+func (g *StaticGateway) serveFile(w http.ResponseWriter, r *http.Request, name string) error {
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	accepted := r.Header.Get("Accept-Encoding")
+	for _, variant := range precompressedVariants {
+		if !acceptsEncoding(accepted, variant.encoding) {
+			continue
+		}
+		served, err := g.serveContent(w, r, name+variant.extension, contentType, variant.encoding)
+		if err != nil || served {
+			return err
+		}
+	}
+
+	served, err := g.serveContent(w, r, name, contentType, "")
+	if err == nil && !served {
+		return fmt.Errorf("file disappeared: %s", name)
+	}
+	return err
+}
+
+// serveContent serves a single stored file, reporting false if it does not exist.
+func (g *StaticGateway) serveContent(w http.ResponseWriter, r *http.Request, name, contentType, encoding string) (bool, error) {
+	file, err := g.storage.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() {
+		return false, nil
+	}
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return false, err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	etag := fmt.Sprintf(`"%x-%x`, info.ModTime().UnixNano(), info.Size())
+	if encoding != "" {
+		etag += "-" + encoding
+	}
+	etag += `"`
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", etag)
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+	return true, nil
+}
+
+// remoteIP returns the client address of a request, or nil when it cannot be parsed.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows the given encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
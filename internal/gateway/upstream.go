@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultConnectTimeout = 5
+	defaultReadTimeout    = 60
+	defaultHealthPath     = "/"
+)
+
+// Upstream proxies requests under a path prefix to a backend instead of serving files.
+// A Path of "/" proxies the whole site; any other prefix must end with "/" and is combined with
+// the deployment's static files, e.g. "/api/" in front of a backend. When URL has a path, the
+// matched prefix is replaced by it, as with nginx's proxy_pass.
+// @Description Backend receiving the requests under a path prefix
+// @Name Upstream
+type Upstream struct {
+	Path           string            `json:"path" validate:"required"`
+	URL            string            `json:"url" validate:"required"`
+	HealthPath     string            `json:"health_path,omitempty"`                                        // Probed before the deployment goes live; defaults to "/"
+	PreserveHost   bool              `json:"preserve_host,omitempty"`                                      // Forward the client's Host header instead of the upstream's
+	Headers        map[string]string `json:"headers,omitempty" validate:"omitempty,max=20"`                // Extra request headers sent to the upstream
+	ConnectTimeout int               `json:"connect_timeout,omitempty" validate:"omitempty,min=1,max=300"` // Seconds; defaults to 5
+	ReadTimeout    int               `json:"read_timeout,omitempty" validate:"omitempty,min=1,max=3600"`   // Seconds between reads and writes; defaults to 60
+}
+
+var (
+	// upstreamPathChars limits prefixes and URL paths to characters safe inside quoted nginx strings and regexes.
+	upstreamPathChars = regexp.MustCompile(`^/[A-Za-z0-9._~!()+,=@%/:-]*$`)
+	upstreamHostChars = regexp.MustCompile(`^[A-Za-z0-9.:\[\]-]+$`)
+
+	// upstreamManagedHeaders are set by the gateway on every proxied request.
+	upstreamManagedHeaders = map[string]bool{
+		"host":              true,
+		"upgrade":           true,
+		"x-forwarded-for":   true,
+		"x-forwarded-host":  true,
+		"x-forwarded-proto": true,
+		"x-real-ip":         true,
+	}
+)
+
+// Validate checks that an upstream can be rendered into nginx configuration safely.
+func (u Upstream) Validate() error {
+	if !upstreamPathChars.MatchString(u.Path) || !strings.HasSuffix(u.Path, "/") {
+		return fmt.Errorf("path %q must start and end with / and contain no spaces, quotes or $", u.Path)
+	}
+
+	target, err := url.Parse(u.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("url %q must be an absolute http(s) URL", u.URL)
+	}
+	if !upstreamHostChars.MatchString(target.Host) || target.User != nil {
+		return fmt.Errorf("url %q has an invalid host", u.URL)
+	}
+	if target.RawQuery != "" || target.Fragment != "" {
+		return fmt.Errorf("url %q must not have a query or fragment", u.URL)
+	}
+	if target.Path != "" && !upstreamPathChars.MatchString(target.EscapedPath()) {
+		return fmt.Errorf("url %q has a path with unsupported characters", u.URL)
+	}
+
+	if u.HealthPath != "" && !upstreamPathChars.MatchString(u.HealthPath) {
+		return fmt.Errorf("health_path %q must be a path starting with /", u.HealthPath)
+	}
+
+	for name, value := range u.Headers {
+		if upstreamManagedHeaders[strings.ToLower(name)] {
+			return fmt.Errorf("header %s is managed by the gateway and cannot be set", name)
+		}
+		if err := validateHeader(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateUpstreams validates every upstream and rejects duplicate path prefixes.
+func ValidateUpstreams(upstreams []Upstream) error {
+	seen := map[string]bool{}
+	for i, upstream := range upstreams {
+		if err := upstream.Validate(); err != nil {
+			return fmt.Errorf("upstream %d: %w", i+1, err)
+		}
+		if seen[upstream.Path] {
+			return fmt.Errorf("upstream %d: path %s is already proxied", i+1, upstream.Path)
+		}
+		seen[upstream.Path] = true
+	}
+	return nil
+}
+
+// HealthURL returns the URL probed to check the upstream is reachable.
+func (u Upstream) HealthURL() string {
+	healthPath := u.HealthPath
+	if healthPath == "" {
+		healthPath = defaultHealthPath
+	}
+	return strings.TrimSuffix(u.URL, "/") + healthPath
+}
+
+// ProxiesSite reports whether an upstream serves every path, leaving no files to serve.
+func ProxiesSite(upstreams []Upstream) bool {
+	for _, upstream := range upstreams {
+		if upstream.Path == "/" {
+			return true
+		}
+	}
+	return false
+}
+
+// upstreamLocations renders one prefix location per upstream. ^~ keeps the prefix ahead of the
+// regex locations used by redirect rules. With a resolver, the upstream is set through a variable so
+// nginx resolves it per request and still loads when the backend's hostname does not resolve yet.
+func upstreamLocations(upstreams []Upstream, resolver string) string {
+	config := ""
+	for _, upstream := range upstreams {
+		target, _ := url.Parse(upstream.URL)
+
+		config += fmt.Sprintf("    location ^~ %s {\n", upstream.Path)
+		if resolver == "" {
+			config += fmt.Sprintf("        proxy_pass %s://%s%s;\n", target.Scheme, target.Host, target.EscapedPath())
+		} else {
+			config += fmt.Sprintf("        resolver %s valid=30s;\n", resolver)
+			config += fmt.Sprintf("        set $infario_upstream \"%s://%s\";\n", target.Scheme, target.Host)
+			if target.Path != "" {
+				config += fmt.Sprintf("        rewrite \"^%s(.*)$\" \"%s$1\" break;\n", regexp.QuoteMeta(upstream.Path), target.EscapedPath())
+			}
+			config += "        proxy_pass $infario_upstream;\n"
+		}
+		config += upstream.proxyDirectives(target.Scheme == "https")
+		config += "    }\n\n"
+	}
+	return config
+}
+
+// proxyDirectives renders the forwarded headers, websocket upgrade and timeouts of an upstream.
+func (u Upstream) proxyDirectives(tls bool) string {
+	host := "$proxy_host"
+	if u.PreserveHost {
+		host = "$host"
+	}
+
+	config := "        proxy_http_version 1.1;\n"
+	config += fmt.Sprintf("        proxy_set_header Host %s;\n", host)
+	config += "        proxy_set_header X-Real-IP $remote_addr;\n"
+	config += "        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n"
+	config += "        proxy_set_header X-Forwarded-Proto $scheme;\n"
+	config += "        proxy_set_header X-Forwarded-Host $host;\n"
+	config += "        proxy_set_header Upgrade $http_upgrade;\n"
+	config += "        proxy_set_header Connection $connection_upgrade;\n"
+
+	names := make([]string, 0, len(u.Headers))
+	for name := range u.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		config += fmt.Sprintf("        proxy_set_header %s \"%s\";\n", name, strings.ReplaceAll(u.Headers[name], `"`, `\"`))
+	}
+
+	connectTimeout, readTimeout := u.timeouts()
+	config += fmt.Sprintf("        proxy_connect_timeout %ds;\n", connectTimeout)
+	config += fmt.Sprintf("        proxy_send_timeout %ds;\n", readTimeout)
+	config += fmt.Sprintf("        proxy_read_timeout %ds;\n", readTimeout)
+	if tls {
+		config += "        proxy_ssl_server_name on;\n"
+	}
+	return config
+}
+
+// timeouts returns the connect and read timeouts in seconds, applying defaults.
+func (u Upstream) timeouts() (connect int, read int) {
+	connect, read = u.ConnectTimeout, u.ReadTimeout
+	if connect == 0 {
+		connect = defaultConnectTimeout
+	}
+	if read == 0 {
+		read = defaultReadTimeout
+	}
+	return connect, read
+}
+
+// upstreamFor returns the upstream with the longest prefix matching a request path, if any.
+func upstreamFor(upstreams []Upstream, requestPath string) *Upstream {
+	var match *Upstream
+	for i, upstream := range upstreams {
+		if strings.HasPrefix(requestPath, upstream.Path) && (match == nil || len(upstream.Path) > len(match.Path)) {
+			match = &upstreams[i]
+		}
+	}
+	return match
+}
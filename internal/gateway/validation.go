@@ -0,0 +1,28 @@
+package gateway
+
+import playground "github.com/go-playground/validator/v10"
+
+// RegisterValidations adds the struct-level checks of redirect, header and upstream rules to v, so
+// rules in request payloads are held to what the gateways can render.
+func RegisterValidations(v *playground.Validate) {
+	v.RegisterStructValidation(func(sl playground.StructLevel) {
+		rule := sl.Current().Interface().(RedirectRule)
+		if err := rule.Validate(); err != nil {
+			sl.ReportError(rule.From, "from", "From", "rule", err.Error())
+		}
+	}, RedirectRule{})
+
+	v.RegisterStructValidation(func(sl playground.StructLevel) {
+		rule := sl.Current().Interface().(HeaderRule)
+		if err := rule.Validate(); err != nil {
+			sl.ReportError(rule.Path, "path", "Path", "rule", err.Error())
+		}
+	}, HeaderRule{})
+
+	v.RegisterStructValidation(func(sl playground.StructLevel) {
+		upstream := sl.Current().Interface().(Upstream)
+		if err := upstream.Validate(); err != nil {
+			sl.ReportError(upstream.Path, "path", "Path", "rule", err.Error())
+		}
+	}, Upstream{})
+}
@@ -0,0 +1,392 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// assemblyPrefix names the staging directories of deployments assembled from uploaded contents.
+	assemblyPrefix = "assembly-"
+	// assemblyFilesName is the file, inside an assembly's staging directory, listing its files.
+	assemblyFilesName = "files.json"
+)
+
+var (
+	// ErrBlobsUnsupported is returned for assemblies when storage does not keep blobs.
+	ErrBlobsUnsupported = errors.New("uploads of individual files require local storage")
+	// ErrAssemblyNotFound is returned for an assembly never begun, already finished or swept.
+	ErrAssemblyNotFound = errors.New("upload session not found")
+	// ErrUnexpectedBlob is returned for uploaded content that no file of the assembly has.
+	ErrUnexpectedBlob = errors.New("content is not part of the upload")
+	// ErrDigestMismatch is returned for uploaded content that does not match its digest.
+	ErrDigestMismatch = errors.New("content does not match its digest and size")
+	// ErrAssemblyBusy is returned when removing an assembly still receiving content or being finished.
+	ErrAssemblyBusy = errors.New("upload session is busy with another request")
+)
+
+// AssemblyFile is a file of a deployment assembled from individually uploaded contents.
+type AssemblyFile struct {
+	Path   string      `json:"path"`   // Slash-separated with a leading slash, e.g. "/assets/app.js"
+	SHA256 string      `json:"sha256"` // Hex-encoded digest of the content
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"` // Permission bits
+}
+
+// MissingBlobsError reports an assembly finished before all of its contents were uploaded.
+type MissingBlobsError struct {
+	Digests []string
+}
+
+func (e *MissingBlobsError) Error() string {
+	return fmt.Sprintf("%d file contents have not been uploaded", len(e.Digests))
+}
+
+// CheckAssembly validates the files of a deployment to assemble against the engine's limits before
+// any content is sent. It returns ErrBlobsUnsupported when storage cannot assemble deployments.
+func (e *FileEngine) CheckAssembly(files []AssemblyFile) error {
+	if e.blobs == nil {
+		return ErrBlobsUnsupported
+	}
+
+	budget := newExtractBudget(e.limits, nil)
+	paths := make(map[string]bool, len(files))
+	dirs := make(map[string]bool)
+	sizes := make(map[string]int64, len(files))
+
+	for _, f := range files {
+		name := strings.TrimPrefix(f.Path, "/")
+		if !strings.HasPrefix(f.Path, "/") || !fs.ValidPath(name) || name == "." {
+			return &EntryError{Name: f.Path, Reason: "path must be absolute and clean, without . or .. segments"}
+		}
+		if !isDigest(f.SHA256) {
+			return &EntryError{Name: f.Path, Reason: "sha256 must be a hex-encoded SHA-256 digest"}
+		}
+		if f.Mode&^fs.ModePerm != 0 || f.Mode&0400 == 0 {
+			return &EntryError{Name: f.Path, Reason: fmt.Sprintf("mode %04o must be permission bits readable by the owner", uint32(f.Mode))}
+		}
+		if size, ok := sizes[f.SHA256]; ok && size != f.Size {
+			return &EntryError{Name: f.Path, Reason: "files with the same digest must have the same size"}
+		}
+		sizes[f.SHA256] = f.Size
+
+		if paths[name] || dirs[name] {
+			return &EntryError{Name: f.Path, Reason: "path is listed twice or is also a directory"}
+		}
+		paths[name] = true
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if paths[dir] {
+				return &EntryError{Name: f.Path, Reason: fmt.Sprintf("/%s is listed as a file", dir)}
+			}
+			dirs[dir] = true
+		}
+
+		if err := budget.entry(name); err != nil {
+			return err
+		}
+		if e.limits.MaxFileBytes > 0 && f.Size > e.limits.MaxFileBytes {
+			return &LimitError{Limit: "file_bytes", Detail: fmt.Sprintf("%s is larger than %d bytes", name, e.limits.MaxFileBytes)}
+		}
+		budget.total += f.Size
+		if e.limits.MaxTotalBytes > 0 && budget.total > e.limits.MaxTotalBytes {
+			return &LimitError{Limit: "total_bytes", Detail: fmt.Sprintf("more than %d bytes uncompressed", e.limits.MaxTotalBytes)}
+		}
+	}
+	return nil
+}
+
+// BeginAssembly starts assembling the deployment id from files. Contents already stored for other
+// deployments are linked in right away, which also keeps them from being freed meanwhile. It returns
+// the digests of the contents still to be uploaded with AddBlob.
+func (e *FileEngine) BeginAssembly(ctx context.Context, id string, files []AssemblyFile) ([]string, error) {
+	if err := e.CheckAssembly(files); err != nil {
+		return nil, err
+	}
+	dir, err := e.assemblyDir(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to reset upload session: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "content"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, assemblyFilesName), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	return e.fillAssembly(dir, files)
+}
+
+// AddBlob stores one uploaded content of the assembly id, verifying it against its digest, and links
+// it into every file that has it. It returns the digests still to be uploaded.
+func (e *FileEngine) AddBlob(ctx context.Context, id, digest string, content io.Reader) ([]string, error) {
+	unlock := e.shareAssembly(id)
+	defer unlock()
+
+	dir, files, err := e.openAssembly(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var expected *AssemblyFile
+	for i := range files {
+		if files[i].SHA256 == digest {
+			expected = &files[i]
+			break
+		}
+	}
+	if expected == nil {
+		return nil, ErrUnexpectedBlob
+	}
+
+	tmp, err := os.CreateTemp(dir, "blob-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(content, expected.Size+1))
+	if err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to receive content: %w", err)
+	}
+	if err := tmp.Chmod(expected.Mode); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write content: %w", err)
+	}
+	if n != expected.Size || hex.EncodeToString(hash.Sum(nil)) != digest {
+		return nil, ErrDigestMismatch
+	}
+
+	// The temporary file holds a reference until every file is linked
+	if err := e.blobs.add(tmp.Name(), digest); err != nil {
+		return nil, fmt.Errorf("failed to store content: %w", err)
+	}
+
+	// Sessions still receiving content are not stale
+	now := time.Now()
+	os.Chtimes(dir, now, now)
+
+	return e.fillAssembly(dir, files)
+}
+
+// FinishAssembly publishes the assembly id as destPath once every content is uploaded, returning
+// a *MissingBlobsError otherwise. It returns the manifest of the stored files.
+func (e *FileEngine) FinishAssembly(ctx context.Context, id, destPath string) ([]FileEntry, error) {
+	unlock := e.shareAssembly(id)
+	defer unlock()
+
+	dir, files, err := e.openAssembly(id)
+	if err != nil {
+		return nil, err
+	}
+
+	missing, err := e.fillAssembly(dir, files)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, &MissingBlobsError{Digests: missing}
+	}
+
+	content := filepath.Join(dir, "content")
+	entries := make([]FileEntry, len(files))
+	for i, f := range files {
+		head, err := readHead(filepath.Join(content, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Path, err)
+		}
+		entries[i] = FileEntry{
+			Path:        f.Path,
+			Size:        f.Size,
+			Mode:        f.Mode,
+			SHA256:      f.SHA256,
+			ContentType: contentType(f.Path, head),
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	if err := e.storage.Import(ctx, destPath, content); err != nil {
+		return nil, fmt.Errorf("failed to store assembled content: %w", err)
+	}
+	os.RemoveAll(dir)
+	e.assemblies.Delete(id)
+	return entries, nil
+}
+
+// RemoveAssembly deletes an unfinished assembly and frees the contents only it used. It reports
+// whether there was an assembly to remove, and returns ErrAssemblyBusy while content is being added
+// to it or it is being finished.
+func (e *FileEngine) RemoveAssembly(ctx context.Context, id string) (bool, error) {
+	mu, _ := e.assemblies.LoadOrStore(id, &sync.RWMutex{})
+	if !mu.(*sync.RWMutex).TryLock() {
+		return false, ErrAssemblyBusy
+	}
+	defer mu.(*sync.RWMutex).Unlock()
+	defer e.assemblies.Delete(id)
+
+	dir, files, err := e.openAssembly(id)
+	if errors.Is(err, ErrAssemblyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return false, fmt.Errorf("failed to remove upload session: %w", err)
+	}
+	digests := make([]string, len(files))
+	for i, f := range files {
+		digests[i] = f.SHA256
+	}
+	if _, err := e.blobs.release(digests); err != nil {
+		return true, fmt.Errorf("failed to free uploaded contents: %w", err)
+	}
+	return true, nil
+}
+
+// StaleAssemblies returns the IDs of assemblies that received no content for maxAge, such as those
+// abandoned by their client. Remove them with RemoveAssembly.
+func (e *FileEngine) StaleAssemblies(maxAge time.Duration) ([]string, error) {
+	if e.blobs == nil {
+		return nil, nil
+	}
+	staging, err := e.stagingDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var ids []string
+	for _, entry := range entries {
+		id, ok := strings.CutPrefix(entry.Name(), assemblyPrefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		// Adding content touches the directory, so its age is the assembly's idle time
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// shareAssembly takes the shared lock of an assembly, held while content is added to it or it is
+// finished so RemoveAssembly cannot pull it away meanwhile.
+func (e *FileEngine) shareAssembly(id string) func() {
+	mu, _ := e.assemblies.LoadOrStore(id, &sync.RWMutex{})
+	mu.(*sync.RWMutex).RLock()
+	return mu.(*sync.RWMutex).RUnlock
+}
+
+// assemblyDir returns the staging directory of the assembly id.
+func (e *FileEngine) assemblyDir(id string) (string, error) {
+	if e.blobs == nil {
+		return "", ErrBlobsUnsupported
+	}
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", fmt.Errorf("invalid upload session id %q", id)
+	}
+	staging, err := e.stagingDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return filepath.Join(staging, assemblyPrefix+id), nil
+}
+
+// openAssembly returns the staging directory and files of a begun assembly.
+func (e *FileEngine) openAssembly(id string) (string, []AssemblyFile, error) {
+	dir, err := e.assemblyDir(id)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, assemblyFilesName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil, ErrAssemblyNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read upload session: %w", err)
+	}
+
+	var files []AssemblyFile
+	if err := json.Unmarshal(data, &files); err != nil {
+		return "", nil, fmt.Errorf("failed to read upload session: %w", err)
+	}
+	return dir, files, nil
+}
+
+// fillAssembly links every stored content into the files of an assembly still without it and
+// returns the digests of the contents not stored yet.
+func (e *FileEngine) fillAssembly(dir string, files []AssemblyFile) ([]string, error) {
+	content := filepath.Join(dir, "content")
+	missing := make(map[string]bool)
+
+	for _, f := range files {
+		dst := filepath.Join(content, filepath.FromSlash(f.Path))
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+		err := e.blobs.place(f.SHA256, dst, f.Mode)
+		if errors.Is(err, fs.ErrNotExist) {
+			missing[f.SHA256] = true
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to assemble %s: %w", f.Path, err)
+		}
+	}
+
+	digests := make([]string, 0, len(missing))
+	for digest := range missing {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+	return digests, nil
+}
+
+// readHead reads the start of a file for content type detection.
+func readHead(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffHeadLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// blobsDirName is the directory, inside local storage, holding file contents keyed by SHA-256.
+const blobsDirName = "blobs"
+
+// blobStore deduplicates deployment files on local storage. Every distinct content is stored once as
+// blobs/{sha[:2]}/{sha} and deployment files are hardlinks to it, so a blob's reference count is its
+// link count minus its own name. The filesystem keeps that count, so it survives crashes and never
+// drifts; a blob is freed once its count drops to zero.
+//
+// When a file cannot be hardlinked, because its mode differs from the blob's or the blob reached the
+// filesystem's link limit, it is reflinked instead where the filesystem supports it and kept as
+// extracted otherwise. Reflinked files share the blob's data but not its inode, so they do not hold
+// a reference.
+type blobStore struct {
+	dir string
+}
+
+func newBlobStore(baseDir string) *blobStore {
+	return &blobStore{dir: filepath.Join(baseDir, blobsDirName)}
+}
+
+// path is where the blob of a digest is stored.
+func (b *blobStore) path(digest string) string {
+	return filepath.Join(b.dir, digest[:2], digest)
+}
+
+// dedup replaces the files of an extracted deployment rooted at root with links to their blobs,
+// adding the blobs of contents not seen before. Files reached through symlinks are linked once.
+// Links are prepared in work, which must be on the same filesystem.
+func (b *blobStore) dedup(root, work string, files []FileEntry) error {
+	tmp := filepath.Join(work, "link")
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		p, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return err
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		if err := b.link(p, tmp, f); err != nil {
+			return fmt.Errorf("failed to deduplicate %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// link points the file at p, described by f, to its blob, preparing the link at tmp.
+func (b *blobStore) link(p, tmp string, f FileEntry) error {
+	blob := b.path(f.SHA256)
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return err
+	}
+
+	for {
+		info, err := os.Lstat(blob)
+		if errors.Is(err, fs.ErrNotExist) {
+			// New content: the file itself becomes the blob
+			if err := os.Link(p, blob); errors.Is(err, fs.ErrExist) {
+				continue // Added concurrently, link to it instead
+			}
+			// Without hardlink support the extracted copy is kept as is
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().Perm() == f.Mode {
+			err := replaceWith(p, tmp, func() error { return os.Link(blob, tmp) })
+			if err == nil {
+				return nil
+			}
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // Freed concurrently, add it again
+			}
+		}
+
+		// Share the data without sharing the inode where the filesystem supports it, otherwise
+		// keep the extracted copy
+		replaceWith(p, tmp, func() error { return cloneFile(blob, tmp, f.Mode) })
+		return nil
+	}
+}
+
+// replaceWith creates tmp with create and renames it over p.
+func replaceWith(p, tmp string, create func() error) error {
+	if err := create(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// add stores the file at p as the blob of digest, unless that content is stored already. p keeps its
+// own link, and so a reference, until it is removed.
+func (b *blobStore) add(p, digest string) error {
+	blob := b.path(digest)
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(p, blob); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// place creates dst with the content of the blob of digest, linked to it when mode matches the blob's.
+// It returns an error wrapping fs.ErrNotExist when the content is not stored.
+func (b *blobStore) place(digest, dst string, mode fs.FileMode) error {
+	blob := b.path(digest)
+	info, err := os.Lstat(blob)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if info.Mode().Perm() == mode {
+		if err := os.Link(blob, dst); err == nil || errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := cloneFile(blob, dst, mode); err == nil {
+		return nil
+	}
+	os.Remove(dst)
+	if err := copyFile(blob, dst); err != nil {
+		return err
+	}
+	return os.Chmod(dst, mode)
+}
+
+// isDigest reports whether s is a hex-encoded SHA-256 digest, and so safe to use as a blob name.
+func isDigest(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// release frees the blobs of digests no deployment links to any more.
+func (b *blobStore) release(digests []string) (int, error) {
+	freed := 0
+	var errs []error
+	for _, digest := range digests {
+		if !isDigest(digest) {
+			continue
+		}
+		ok, err := b.freeIfUnused(b.path(digest))
+		if ok {
+			freed++
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return freed, errors.Join(errs...)
+}
+
+// sweep frees every blob no deployment links to, such as those of replaced or partially removed
+// deployments.
+func (b *blobStore) sweep() (int, error) {
+	freed := 0
+	var errs []error
+	err := filepath.WalkDir(b.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ok, err := b.freeIfUnused(p)
+		if ok {
+			freed++
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return freed, errors.Join(errs...)
+}
+
+// freeIfUnused removes the blob at p when it is its inode's only link. A deployment linking it
+// concurrently keeps its own link to the content, so at worst the content is stored again later.
+func (b *blobStore) freeIfUnused(p string) (bool, error) {
+	info, err := os.Lstat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if linkCount(info) > 1 {
+		return false, nil
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseBlobs frees the stored contents of digests once no deployment uses them any more. Call it
+// with a deployment's digests after removing its files. It returns how many blobs were freed.
+func (e *FileEngine) ReleaseBlobs(ctx context.Context, digests []string) (int, error) {
+	if e.blobs == nil {
+		return 0, nil
+	}
+	return e.blobs.release(digests)
+}
+
+// SweepBlobs frees every stored content no deployment uses any more. It returns how many blobs were
+// freed.
+func (e *FileEngine) SweepBlobs(ctx context.Context) (int, error) {
+	if e.blobs == nil {
+		return 0, nil
+	}
+	return e.blobs.sweep()
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package engine
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// blobsSupported reports whether the platform exposes the link counts blobs are reference counted by.
+const blobsSupported = false
+
+func linkCount(info fs.FileInfo) uint64 {
+	return 1
+}
+
+func cloneFile(src, dst string, mode fs.FileMode) error {
+	return errors.ErrUnsupported
+}
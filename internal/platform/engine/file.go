@@ -3,118 +3,181 @@ package engine
 import (
 	"archive/tar"
 	"archive/zip"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
-// FileEngine handles archive extraction and filesystem operations.
+// FileEngine handles archive extraction and file operations on top of a Storage backend.
 type FileEngine struct {
-	BaseDir string
+	storage Storage
+	limits  Limits
+	blobs   *blobStore // Deduplicates files across deployments; nil unless storage is local
+	uploads sync.Map   // Locks of resumable uploads, keyed by upload ID
+
+	assemblies sync.Map // Locks of assemblies, keyed by assembly ID
 }
 
-// NewFileEngine creates a new FileEngine with the given base directory.
-func NewFileEngine(baseDir string) *FileEngine {
-	return &FileEngine{BaseDir: baseDir}
+// NewFileEngine creates a new FileEngine storing files in the given backend.
+// Archives exceeding limits are rejected with a *LimitError.
+// On local storage, files with the same content are stored once and shared between deployments.
+func NewFileEngine(storage Storage, limits Limits) *FileEngine {
+	e := &FileEngine{storage: storage, limits: limits}
+	if local, ok := storage.(*LocalStorage); ok && blobsSupported {
+		e.blobs = newBlobStore(local.BaseDir)
+	}
+	return e
 }
 
-// Extract extracts archive content (zip or tar.gz) into the destination directory.
+// Extract extracts an archive into the destination directory. The format is detected from the
+// archive's content and must be one of allowed, or any supported format when allowed is empty.
 // If the archive contains a single root directory, its contents are extracted directly.
 // Otherwise, all archive contents are extracted as-is.
-// destPath should be relative to BaseDir (e.g., "deployments/project-id/deployment-id").
-// Automatically detects format from filename extension.
-func (e *FileEngine) Extract(ctx context.Context, destPath string, archiveData io.Reader, filename string) error {
-	fullDestPath := filepath.Join(e.BaseDir, destPath)
-	tempPath := filepath.Join(e.BaseDir, destPath+"_extract_tmp")
-
-	// Create temporary directory for extraction
-	if err := os.MkdirAll(tempPath, 0755); err != nil {
-		return fmt.Errorf("failed to create temporary directory: %w", err)
+// destPath is a storage name (e.g., "deployments/project-id/deployment-id").
+// Extraction stops with a *LimitError as soon as the archive exceeds the engine's limits.
+// It returns the manifest of the stored files, recorded while extracting.
+func (e *FileEngine) Extract(ctx context.Context, destPath string, archiveData io.Reader, filename string, allowed []Format) ([]FileEntry, error) {
+	// Extract to a staging directory, then hand it to the storage backend to publish
+	staging, err := e.stagingDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	tempPath, err := os.MkdirTemp(staging, "extract-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 	defer os.RemoveAll(tempPath)
 
-	// Detect format and extract to temporary location
-	if strings.HasSuffix(filename, ".tar.gz") || strings.HasSuffix(filename, ".tgz") {
-		if err := e.extractTarGz(archiveData, tempPath); err != nil {
-			return fmt.Errorf("tar.gz extraction failed: %w", err)
-		}
-	} else if strings.HasSuffix(filename, ".zip") {
-		if err := e.unzip(archiveData, tempPath); err != nil {
-			return fmt.Errorf("zip extraction failed: %w", err)
+	compressed := &countingReader{r: archiveData, max: e.limits.MaxArchiveBytes}
+	ex := newExtraction(newExtractBudget(e.limits, compressed))
+
+	format, src, err := sniffFormat(compressed, filename, allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract to temporary location
+	if format == FormatZip {
+		if err := e.unzip(src, tempPath, ex); err != nil {
+			return nil, fmt.Errorf("zip extraction failed: %w", err)
 		}
-	} else {
-		return fmt.Errorf("unsupported archive format: %s", filename)
+	} else if err := e.extractTar(format, src, tempPath, ex); err != nil {
+		return nil, fmt.Errorf("%s extraction failed: %w", format, err)
 	}
 
 	// Check if extracted content has a single root directory
 	entries, err := os.ReadDir(tempPath)
 	if err != nil {
-		return fmt.Errorf("failed to read extracted contents: %w", err)
+		return nil, fmt.Errorf("failed to read extracted contents: %w", err)
 	}
 
-	// If single root directory, move its contents up one level
+	// If single root directory, import its contents instead
+	root := tempPath
 	if len(entries) == 1 && entries[0].IsDir() {
 		singleDirPath := filepath.Join(tempPath, entries[0].Name())
 		subEntries, err := os.ReadDir(singleDirPath)
 		if err == nil && len(subEntries) > 0 {
-			// Create destination and move contents from subdirectory
-			if err := os.MkdirAll(fullDestPath, 0755); err != nil {
-				return fmt.Errorf("failed to create destination directory: %w", err)
-			}
-			for _, entry := range subEntries {
-				src := filepath.Join(singleDirPath, entry.Name())
-				dst := filepath.Join(fullDestPath, entry.Name())
-				if err := os.Rename(src, dst); err != nil {
-					return fmt.Errorf("failed to move extracted content: %w", err)
-				}
-			}
-			return nil
+			root = singleDirPath
 		}
 	}
 
-	// Otherwise, move all extracted content to destination
-	if err := os.MkdirAll(fullDestPath, 0755); err != nil {
-		return fmt.Errorf("failed to create destination directory: %w", err)
+	// Symlinks must resolve inside the deployment root as it will be stored
+	if err := verifySymlinks(root, e.limits); err != nil {
+		return nil, err
+	}
+
+	// The staging directory may become the published root; MkdirTemp creates it private
+	if err := os.Chmod(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to set permissions: %w", err)
+	}
+
+	files, err := ex.manifest(root)
+	if err != nil {
+		return nil, err
 	}
-	for _, entry := range entries {
-		src := filepath.Join(tempPath, entry.Name())
-		dst := filepath.Join(fullDestPath, entry.Name())
-		if err := os.Rename(src, dst); err != nil {
-			return fmt.Errorf("failed to move extracted content: %w", err)
+
+	// Share the contents already stored for other deployments
+	if e.blobs != nil {
+		work, err := os.MkdirTemp(staging, "dedup-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(work)
+		if err := e.blobs.dedup(root, work, files); err != nil {
+			return nil, err
 		}
 	}
 
-	return nil
+	if err := e.storage.Import(ctx, destPath, root); err != nil {
+		return nil, fmt.Errorf("failed to store extracted content: %w", err)
+	}
+	return files, nil
 }
 
 // Exists checks if the given path exists in storage (file or directory).
-// path should be relative to BaseDir.
 func (e *FileEngine) Exists(ctx context.Context, path string) bool {
-	fullPath := filepath.Join(e.BaseDir, path)
-	_, err := os.Stat(fullPath)
+	_, err := e.storage.Stat(ctx, path)
 	return err == nil
 }
 
-// Remove cleans up assets from the disk at the given path.
-// path should be relative to BaseDir.
+// IsDir checks if the given path exists in storage and is a directory.
+func (e *FileEngine) IsDir(ctx context.Context, path string) bool {
+	info, err := e.storage.Stat(ctx, path)
+	return err == nil && info.IsDir()
+}
+
+// Stat describes a file or directory in storage.
+func (e *FileEngine) Stat(ctx context.Context, path string) (fs.FileInfo, error) {
+	return e.storage.Stat(ctx, path)
+}
+
+// Open reads a file from storage.
+func (e *FileEngine) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	return e.storage.Open(ctx, path)
+}
+
+// List returns the names of every file below path.
+func (e *FileEngine) List(ctx context.Context, path string) ([]string, error) {
+	return e.storage.List(ctx, path)
+}
+
+// Remove cleans up assets from storage at the given path. Contents shared with other deployments
+// stay stored until released with ReleaseBlobs.
 func (e *FileEngine) Remove(ctx context.Context, path string) error {
-	fullPath := filepath.Join(e.BaseDir, path)
-	return os.RemoveAll(fullPath)
+	return e.storage.RemoveAll(ctx, path)
 }
 
-// extractTarGz safely extracts tar.gz content to destination directory with ZIP SLIP protection.
-func (e *FileEngine) extractTarGz(src io.Reader, dest string) error {
-	gzr, err := gzip.NewReader(src)
+// FS returns a read-only view of storage.
+func (e *FileEngine) FS() fs.FS {
+	if local, ok := e.storage.(*LocalStorage); ok {
+		return os.DirFS(local.BaseDir)
+	}
+	return newStorageFS(e.storage)
+}
+
+// stagingDir returns where archives are extracted before import. Local storage stages inside BaseDir
+// so the import is a rename; other backends stage in the system temp directory.
+func (e *FileEngine) stagingDir() (string, error) {
+	if local, ok := e.storage.(*LocalStorage); ok {
+		return local.stagingDir()
+	}
+	return ensureStagingDir(filepath.Join(os.TempDir(), "infario"))
+}
+
+// extractTar safely extracts a tar archive, optionally compressed, to destination directory with ZIP SLIP protection.
+func (e *FileEngine) extractTar(format Format, src io.Reader, dest string, ex *extraction) error {
+	decompressed, err := decompressTar(format, src)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return fmt.Errorf("failed to create %s reader: %w", format, err)
 	}
-	defer gzr.Close()
+	defer decompressed.Close()
 
-	tr := tar.NewReader(gzr)
+	tr := tar.NewReader(decompressed)
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -124,19 +187,31 @@ func (e *FileEngine) extractTarGz(src io.Reader, dest string) error {
 			return fmt.Errorf("failed to read tar entry: %w", err)
 		}
 
+		// Global pax headers carry metadata only
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
 		// ZIP SLIP protection: ensure file path is within destination
 		path := filepath.Join(dest, header.Name)
 		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
 			return fmt.Errorf("illegal file path: %s", header.Name)
 		}
+		if err := ex.entry(header.Name); err != nil {
+			return err
+		}
 
-		if header.Typeflag == tar.TypeDir {
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := prepareEntry(dest, path, header.Name); err != nil {
+				return err
+			}
 			if err := os.MkdirAll(path, 0755); err != nil {
 				return fmt.Errorf("failed to create directory: %w", err)
 			}
-		} else {
-			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-				return fmt.Errorf("failed to create parent directory: %w", err)
+		case tar.TypeReg:
+			if err := prepareEntry(dest, path, header.Name); err != nil {
+				return err
 			}
 
 			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -144,11 +219,23 @@ func (e *FileEngine) extractTarGz(src io.Reader, dest string) error {
 				return fmt.Errorf("failed to create file: %w", err)
 			}
 
-			if _, err := io.Copy(file, tr); err != nil {
+			if err := ex.copy(file, tr, header.Name); err != nil {
 				file.Close()
 				return fmt.Errorf("failed to write file: %w", err)
 			}
 			file.Close()
+		case tar.TypeSymlink:
+			if err := createSymlink(dest, path, header.Name, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := createHardlink(dest, path, header.Name, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			return &EntryError{Name: header.Name, Reason: "device and FIFO entries are not allowed"}
+		default:
+			return &EntryError{Name: header.Name, Reason: fmt.Sprintf("unsupported entry type %q", header.Typeflag)}
 		}
 	}
 
@@ -157,8 +244,12 @@ func (e *FileEngine) extractTarGz(src io.Reader, dest string) error {
 
 // unzip safely extracts zip content to destination directory.
 // Buffers the stream to a temporary file since zip.NewReader requires ReaderAt.
-func (e *FileEngine) unzip(src io.Reader, dest string) error {
-	tmpZip, err := os.CreateTemp("", "infario-upload-*.zip")
+func (e *FileEngine) unzip(src io.Reader, dest string, ex *extraction) error {
+	staging, err := e.stagingDir()
+	if err != nil {
+		return err
+	}
+	tmpZip, err := os.CreateTemp(staging, "zip-*.zip")
 	if err != nil {
 		return err
 	}
@@ -176,7 +267,7 @@ func (e *FileEngine) unzip(src io.Reader, dest string) error {
 	}
 
 	for _, f := range reader.File {
-		if err := e.extractFile(f, dest); err != nil {
+		if err := e.extractFile(f, dest, ex); err != nil {
 			return err
 		}
 	}
@@ -185,18 +276,35 @@ func (e *FileEngine) unzip(src io.Reader, dest string) error {
 }
 
 // extractFile safely extracts a single file from a zip with ZIP SLIP protection.
-func (e *FileEngine) extractFile(f *zip.File, dest string) error {
+func (e *FileEngine) extractFile(f *zip.File, dest string, ex *extraction) error {
 
 	path := filepath.Join(dest, f.Name)
 	if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
 		return fmt.Errorf("illegal file path: %s", f.Name)
 	}
+	if err := ex.entry(f.Name); err != nil {
+		return err
+	}
 
-	if f.FileInfo().IsDir() {
-		return os.MkdirAll(path, f.Mode())
+	mode := f.Mode()
+	if mode.IsDir() {
+		if err := prepareEntry(dest, path, f.Name); err != nil {
+			return err
+		}
+		return os.MkdirAll(path, mode)
+	}
+	if mode&fs.ModeSymlink != 0 {
+		target, err := readZipLink(f)
+		if err != nil {
+			return err
+		}
+		return createSymlink(dest, path, f.Name, target)
+	}
+	if mode&(fs.ModeDevice|fs.ModeCharDevice|fs.ModeNamedPipe|fs.ModeSocket|fs.ModeIrregular) != 0 {
+		return &EntryError{Name: f.Name, Reason: "device, FIFO and socket entries are not allowed"}
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	if err := prepareEntry(dest, path, f.Name); err != nil {
 		return err
 	}
 
@@ -212,6 +320,20 @@ func (e *FileEngine) extractFile(f *zip.File, dest string) error {
 	}
 	defer srcFile.Close()
 
-	_, err = io.Copy(dstFile, srcFile)
-	return err
+	return ex.copy(dstFile, srcFile, f.Name)
+}
+
+// readZipLink reads a zip symlink entry, whose content is the link target.
+func readZipLink(f *zip.File) (string, error) {
+	src, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	target, err := io.ReadAll(io.LimitReader(src, 4096))
+	if err != nil {
+		return "", err
+	}
+	return string(target), nil
 }
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Format identifies an archive format accepted for deployments.
+type Format string
+
+const (
+	FormatZip    Format = "zip"
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarBz2 Format = "tar.bz2"
+	FormatTarZst Format = "tar.zst"
+	FormatTarXz  Format = "tar.xz"
+)
+
+// Formats lists every supported archive format.
+var Formats = []Format{FormatZip, FormatTar, FormatTarGz, FormatTarBz2, FormatTarZst, FormatTarXz}
+
+// sniffLen covers the tar header's "ustar" magic at offset 257.
+const sniffLen = 512
+
+// zstdMaxWindow bounds the memory a zstd stream may ask the decoder to allocate.
+const zstdMaxWindow = 64 << 20
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte{0x1f, 0x8b}
+	bzip2Magic    = []byte("BZh")
+	xzMagic       = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	tarMagic      = []byte("ustar")
+)
+
+// FormatError reports an archive whose format is unknown or not allowed.
+type FormatError struct {
+	Format  Format // Empty when the format could not be detected
+	Allowed []Format
+}
+
+func (e *FormatError) Error() string {
+	if e.Format == "" {
+		return "archive format not recognized; upload a zip or tar archive"
+	}
+	allowed := make([]string, len(e.Allowed))
+	for i, format := range e.Allowed {
+		allowed[i] = string(format)
+	}
+	return fmt.Sprintf("archive format %s is not allowed; allowed formats: %s", e.Format, strings.Join(allowed, ", "))
+}
+
+// detectFormat identifies an archive from its leading bytes. Compressed streams are assumed to hold
+// a tar archive. The filename is only consulted for pre-POSIX tar archives, which have no magic.
+func detectFormat(header []byte, filename string) (Format, bool) {
+	switch {
+	case bytes.HasPrefix(header, zipMagic), bytes.HasPrefix(header, zipEmptyMagic):
+		return FormatZip, true
+	case bytes.HasPrefix(header, gzipMagic):
+		return FormatTarGz, true
+	case bytes.HasPrefix(header, bzip2Magic):
+		return FormatTarBz2, true
+	case bytes.HasPrefix(header, xzMagic):
+		return FormatTarXz, true
+	case bytes.HasPrefix(header, zstdMagic):
+		return FormatTarZst, true
+	case len(header) >= 262 && bytes.Equal(header[257:262], tarMagic):
+		return FormatTar, true
+	case strings.HasSuffix(strings.ToLower(filename), ".tar"):
+		return FormatTar, true
+	}
+	return "", false
+}
+
+// sniffFormat detects the format of an archive stream and checks it against allowed, where an empty
+// list allows every format. The returned reader replays the inspected bytes.
+func sniffFormat(src io.Reader, filename string, allowed []Format) (Format, io.Reader, error) {
+	buffered := bufio.NewReaderSize(src, sniffLen)
+	header, err := buffered.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", nil, fmt.Errorf("failed to read archive header: %w", err)
+	}
+
+	format, ok := detectFormat(header, filename)
+	if !ok {
+		return "", nil, &FormatError{Allowed: allowed}
+	}
+	if len(allowed) > 0 && !slices.Contains(allowed, format) {
+		return "", nil, &FormatError{Format: format, Allowed: allowed}
+	}
+	return format, buffered, nil
+}
+
+// decompressTar wraps a compressed tar stream in its decompressor.
+func decompressTar(format Format, src io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case FormatTar:
+		return io.NopCloser(src), nil
+	case FormatTarGz:
+		return gzip.NewReader(src)
+	case FormatTarBz2:
+		return io.NopCloser(bzip2.NewReader(src)), nil
+	case FormatTarXz:
+		r, err := xz.NewReader(src)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(r), nil
+	case FormatTarZst:
+		d, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported archive format: %s", format)
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ratioFloor is how many bytes may be extracted before the compression ratio is enforced, so small
+// archives of highly compressible text are not rejected.
+const ratioFloor = 1 << 20
+
+// Limits bounds the resources an archive may consume while being extracted. Zero disables a limit.
+type Limits struct {
+	MaxArchiveBytes int64   // Compressed bytes of the archive itself
+	MaxTotalBytes   int64   // Uncompressed bytes across all entries
+	MaxFileBytes    int64   // Uncompressed bytes of a single entry
+	MaxFiles        int     // Entries, including directories
+	MaxDepth        int     // Path segments of an entry, e.g. "a/b/c.txt" has 3
+	MaxRatio        float64 // Uncompressed bytes per compressed byte of the archive
+}
+
+// LimitError reports an archive that exceeded an extraction limit.
+type LimitError struct {
+	Limit  string // Which limit was exceeded, e.g. "total_bytes"
+	Detail string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("archive exceeds %s limit: %s", e.Limit, e.Detail)
+}
+
+// extractBudget tracks what an extraction has consumed against its limits.
+type extractBudget struct {
+	limits     Limits
+	compressed *countingReader
+	total      int64
+	files      int
+}
+
+func newExtractBudget(limits Limits, compressed *countingReader) *extractBudget {
+	return &extractBudget{limits: limits, compressed: compressed}
+}
+
+// entry accounts for a new archive entry and checks its path depth.
+func (b *extractBudget) entry(name string) error {
+	b.files++
+	if b.limits.MaxFiles > 0 && b.files > b.limits.MaxFiles {
+		return &LimitError{Limit: "file_count", Detail: fmt.Sprintf("more than %d entries", b.limits.MaxFiles)}
+	}
+
+	depth := len(strings.Split(strings.Trim(name, "/"), "/"))
+	if b.limits.MaxDepth > 0 && depth > b.limits.MaxDepth {
+		return &LimitError{Limit: "path_depth", Detail: fmt.Sprintf("%s is nested deeper than %d levels", name, b.limits.MaxDepth)}
+	}
+	return nil
+}
+
+// copy streams one entry to dst, aborting as soon as a size or ratio limit is crossed.
+func (b *extractBudget) copy(dst io.Writer, src io.Reader, name string) error {
+	_, err := io.Copy(&budgetWriter{budget: b, dst: dst, name: name}, src)
+	return err
+}
+
+// budgetWriter checks limits before every write of an entry.
+type budgetWriter struct {
+	budget  *extractBudget
+	dst     io.Writer
+	name    string
+	written int64
+}
+
+func (w *budgetWriter) Write(p []byte) (int, error) {
+	b := w.budget
+	n := int64(len(p))
+
+	if b.limits.MaxFileBytes > 0 && w.written+n > b.limits.MaxFileBytes {
+		return 0, &LimitError{Limit: "file_bytes", Detail: fmt.Sprintf("%s is larger than %d bytes", w.name, b.limits.MaxFileBytes)}
+	}
+	if b.limits.MaxTotalBytes > 0 && b.total+n > b.limits.MaxTotalBytes {
+		return 0, &LimitError{Limit: "total_bytes", Detail: fmt.Sprintf("more than %d bytes uncompressed", b.limits.MaxTotalBytes)}
+	}
+	if b.limits.MaxRatio > 0 && b.compressed != nil && b.total+n > ratioFloor {
+		if compressed := b.compressed.n; compressed > 0 && float64(b.total+n) > b.limits.MaxRatio*float64(compressed) {
+			return 0, &LimitError{Limit: "compression_ratio", Detail: fmt.Sprintf("expands more than %gx", b.limits.MaxRatio)}
+		}
+	}
+
+	written, err := w.dst.Write(p)
+	w.written += int64(written)
+	b.total += int64(written)
+	return written, err
+}
+
+// countingReader counts the bytes read through it, failing once more than max are read.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	max int64 // Zero for no limit
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.max > 0 && c.n > c.max {
+		return n, &LimitError{Limit: "archive_bytes", Detail: fmt.Sprintf("larger than %d bytes", c.max)}
+	}
+	return n, err
+}
@@ -0,0 +1,254 @@
+package engine
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinks bounds how many symlinks resolving one path may follow, as the kernel's ELOOP limit does.
+const maxSymlinks = 40
+
+// EntryError reports an archive entry rejected by the extraction policy.
+type EntryError struct {
+	Name   string // Entry name as it appears in the archive
+	Reason string
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("archive entry %s rejected: %s", e.Name, e.Reason)
+}
+
+// createSymlink creates a relative symlink whose target lies inside root.
+func createSymlink(root, path, name, target string) error {
+	if target == "" || filepath.IsAbs(target) || strings.HasPrefix(target, "/") {
+		return &EntryError{Name: name, Reason: fmt.Sprintf("symlink target %q must be a relative path", target)}
+	}
+	if !within(root, filepath.Join(filepath.Dir(path), target)) {
+		return &EntryError{Name: name, Reason: fmt.Sprintf("symlink target %q points outside the deployment root", target)}
+	}
+	if err := prepareEntry(root, path, name); err != nil {
+		return err
+	}
+	return os.Symlink(target, path)
+}
+
+// createHardlink links path to a file extracted earlier from the same archive.
+func createHardlink(root, path, name, linkname string) error {
+	if !within(root, filepath.Join(root, linkname)) {
+		return &EntryError{Name: name, Reason: fmt.Sprintf("hardlink target %q points outside the deployment root", linkname)}
+	}
+	resolved, err := resolveInRoot(root, linkname)
+	if err != nil {
+		return &EntryError{Name: name, Reason: fmt.Sprintf("hardlink target %q: %v", linkname, err)}
+	}
+	source := filepath.Join(root, resolved)
+	info, err := os.Stat(source)
+	if err != nil || !info.Mode().IsRegular() {
+		return &EntryError{Name: name, Reason: fmt.Sprintf("hardlink target %q is not a file extracted earlier", linkname)}
+	}
+	if err := prepareEntry(root, path, name); err != nil {
+		return err
+	}
+
+	if err := os.Link(source, path); err != nil {
+		return copyFile(source, path)
+	}
+	return nil
+}
+
+// prepareEntry creates the parent directories of an entry, checks they resolve inside root so nothing
+// is written through a symlink, and removes a symlink previously extracted at the same path.
+func prepareEntry(root, path, name string) error {
+	parent := filepath.Dir(path)
+	rel, err := filepath.Rel(root, parent)
+	if err != nil {
+		return err
+	}
+	if _, err := resolveInRoot(root, rel); err != nil {
+		return &EntryError{Name: name, Reason: err.Error()}
+	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// verifySymlinks checks, after extraction, that every symlink below root resolves to an existing
+// path inside root and that following them never loops, e.g. through links pointing at each other's
+// directories. Resolution never looks above root, so links stay valid once root is stored under
+// another name. Files reached through directory symlinks are served once per path, so every path
+// counts against the file and total size limits.
+func verifySymlinks(root string, limits Limits) error {
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+
+		target, err := resolveInRoot(root, name)
+		if err != nil {
+			return &EntryError{Name: name, Reason: err.Error()}
+		}
+		if _, err := os.Stat(filepath.Join(root, target)); err != nil {
+			return &EntryError{Name: name, Reason: "symlink does not resolve to an existing file"}
+		}
+
+		parent, err := resolveInRoot(root, path.Dir(name))
+		if err != nil {
+			return &EntryError{Name: name, Reason: err.Error()}
+		}
+		if location := path.Join(parent, d.Name()); target == "." || strings.HasPrefix(location, target+"/") {
+			return &EntryError{Name: name, Reason: "symlink points at its own parent directory"}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var files int
+	var total int64
+	return walkFiles(root, func(name, p string) error {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		files++
+		total += info.Size()
+
+		if limits.MaxFiles > 0 && files > limits.MaxFiles {
+			return &LimitError{Limit: "file_count", Detail: fmt.Sprintf("more than %d files once symlinks are followed", limits.MaxFiles)}
+		}
+		if limits.MaxTotalBytes > 0 && total > limits.MaxTotalBytes {
+			return &LimitError{Limit: "total_bytes", Detail: fmt.Sprintf("more than %d bytes once symlinks are followed", limits.MaxTotalBytes)}
+		}
+		return nil
+	})
+}
+
+// resolveInRoot resolves the symlinks of a slash-separated name relative to root as if root were the
+// filesystem root, failing when ".." or a link target would leave it. Missing components are left as
+// they are, so names that do not exist yet can be checked.
+func resolveInRoot(root, name string) (string, error) {
+	var resolved []string
+	pending := strings.Split(filepath.ToSlash(name), "/")
+	links := 0
+
+	for len(pending) > 0 {
+		part := pending[0]
+		pending = pending[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return "", fmt.Errorf("path resolves outside the deployment root")
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		current := append(resolved, part)
+		full := filepath.Join(root, filepath.FromSlash(strings.Join(current, "/")))
+		info, err := os.Lstat(full)
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			resolved = current
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symlinks")
+		}
+		target, err := os.Readlink(full)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			return "", fmt.Errorf("symlink target %q must be a relative path", target)
+		}
+		pending = append(strings.Split(filepath.ToSlash(target), "/"), pending...)
+	}
+
+	if len(resolved) == 0 {
+		return ".", nil
+	}
+	return strings.Join(resolved, "/"), nil
+}
+
+// within reports whether path lies strictly below root, comparing cleaned paths.
+func within(root, path string) bool {
+	return strings.HasPrefix(filepath.Clean(path), filepath.Clean(root)+string(os.PathSeparator))
+}
+
+// walkFiles calls fn for every file below root, following symlinks. Names are slash-separated and
+// relative to root. A symlink leading back into a directory the walk is inside fails with an
+// *EntryError instead of recursing forever.
+func walkFiles(root string, fn func(name, path string) error) error {
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	return walkFilesAt(root, "", []string{resolved}, fn)
+}
+
+// walkFilesAt walks dir, naming its files below prefix. entered holds the resolved parent directories
+// of the symlinks followed to reach dir; a directory symlink resolving to one of them, or to one of
+// their ancestors, is a cycle.
+func walkFilesAt(dir, prefix string, entered []string, fn func(name, path string) error) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(filepath.Join(prefix, rel))
+
+		switch {
+		case d.IsDir():
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := filepath.EvalSymlinks(p)
+			if err != nil {
+				return err
+			}
+			info, err := os.Stat(target)
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return fn(name, p)
+			}
+
+			parent, err := filepath.EvalSymlinks(filepath.Dir(p))
+			if err != nil {
+				return err
+			}
+			chain := append(entered[:len(entered):len(entered)], parent)
+			for _, inside := range chain {
+				if inside == target || within(target, inside) {
+					return &EntryError{Name: name, Reason: "symlink leads back into a directory containing it"}
+				}
+			}
+			return walkFilesAt(target, name, chain, fn)
+		case d.Type().IsRegular():
+			return fn(name, p)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// tarEntry is a file, directory or symlink of a test archive.
+type tarEntry struct {
+	name     string
+	content  string
+	linkname string // Makes the entry a symlink
+}
+
+func buildTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		switch {
+		case entry.linkname != "":
+			header = &tar.Header{Name: entry.name, Mode: 0777, Linkname: entry.linkname, Typeflag: tar.TypeSymlink}
+		case entry.name[len(entry.name)-1] == '/':
+			header = &tar.Header{Name: entry.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// extractWithin extracts an archive to local storage, failing the test if it does not finish in time.
+func extractWithin(t *testing.T, archive *bytes.Buffer, limits Limits) ([]FileEntry, error) {
+	t.Helper()
+
+	engine := NewFileEngine(NewLocalStorage(t.TempDir()), limits)
+	type result struct {
+		files []FileEntry
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		files, err := engine.Extract(context.Background(), "deployments/p/d", archive, "site.tar", nil)
+		done <- result{files, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.files, r.err
+	case <-time.After(10 * time.Second):
+		t.Fatal("extraction did not finish")
+		return nil, nil
+	}
+}
+
+func TestExtractRejectsSymlinkCycle(t *testing.T) {
+	archive := buildTar(t, []tarEntry{
+		{name: "a/"},
+		{name: "b/"},
+		{name: "a/index.html", content: "a"},
+		{name: "b/index.html", content: "b"},
+		{name: "a/link", linkname: "../b"},
+		{name: "b/link", linkname: "../a"},
+	})
+
+	_, err := extractWithin(t, archive, Limits{})
+	var entryErr *EntryError
+	if !errors.As(err, &entryErr) {
+		t.Fatalf("Extract = %v, want an *EntryError", err)
+	}
+}
+
+func TestExtractFollowsSharedDirectorySymlinks(t *testing.T) {
+	archive := buildTar(t, []tarEntry{
+		{name: "shared/"},
+		{name: "shared/logo.svg", content: "<svg/>"},
+		{name: "en/"},
+		{name: "de/"},
+		{name: "en/assets", linkname: "../shared"},
+		{name: "de/assets", linkname: "../shared"},
+		{name: "index.html", content: "hi"},
+	})
+
+	files, err := extractWithin(t, archive, Limits{})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Path)
+	}
+	sort.Strings(names)
+	want := []string{"/de/assets/logo.svg", "/en/assets/logo.svg", "/index.html", "/shared/logo.svg"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("manifest = %q, want %q", names, want)
+	}
+}
+
+func TestExtractLimitsSymlinkFanOut(t *testing.T) {
+	// Each level links to the next one twice, so level 0 serves 2^16 copies of the last level's file
+	const levels = 16
+	var entries []tarEntry
+	for i := 0; i < levels; i++ {
+		level := fmt.Sprintf("l%d/", i)
+		entries = append(entries,
+			tarEntry{name: level},
+			tarEntry{name: level + "a", linkname: fmt.Sprintf("../l%d", i+1)},
+			tarEntry{name: level + "b", linkname: fmt.Sprintf("../l%d", i+1)},
+		)
+	}
+	entries = append(entries, tarEntry{name: fmt.Sprintf("l%d/", levels)}, tarEntry{name: fmt.Sprintf("l%d/index.html", levels), content: "hi"})
+
+	_, err := extractWithin(t, buildTar(t, entries), Limits{MaxFiles: 100})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "file_count" {
+		t.Fatalf("Extract = %v, want a file_count *LimitError", err)
+	}
+}
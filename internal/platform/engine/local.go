@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStorage keeps files in a directory on the local filesystem.
+type LocalStorage struct {
+	BaseDir string
+}
+
+// NewLocalStorage creates a storage rooted at baseDir.
+func NewLocalStorage(baseDir string) *LocalStorage {
+	return &LocalStorage{BaseDir: baseDir}
+}
+
+// Import publishes dir as dest with a single rename, so dest is never seen half-populated. An existing
+// dest is replaced, atomically where the filesystem can exchange directories, and is only removed once
+// the new content is in place. dir should be on the same filesystem as BaseDir; otherwise it is first
+// copied to the staging directory, keeping the final rename atomic.
+func (s *LocalStorage) Import(ctx context.Context, dest, dir string) error {
+	fullDest := s.path(dest)
+	if err := os.MkdirAll(filepath.Dir(fullDest), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	staging, err := s.stagingDir()
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	work, err := os.MkdirTemp(staging, "import-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	keepWork := false
+	defer func() {
+		if !keepWork {
+			os.RemoveAll(work)
+		}
+	}()
+
+	content := filepath.Join(work, "content")
+	if err := os.Rename(dir, content); err != nil {
+		if err := copyTree(dir, content); err != nil {
+			return fmt.Errorf("failed to copy extracted content: %w", err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove extracted content: %w", err)
+		}
+	}
+
+	if _, err := os.Lstat(fullDest); err != nil {
+		if err := os.Rename(content, fullDest); err != nil {
+			return fmt.Errorf("failed to publish extracted content: %w", err)
+		}
+		return nil
+	}
+
+	// Swap in the new content; the previous version ends up in the work directory and is removed with it
+	if err := exchange(content, fullDest); err == nil {
+		return nil
+	}
+
+	// Without exchange support, move the previous version aside and put it back if publishing fails
+	previous := filepath.Join(work, "previous")
+	if err := os.Rename(fullDest, previous); err != nil {
+		return fmt.Errorf("failed to replace existing content: %w", err)
+	}
+	if err := os.Rename(content, fullDest); err != nil {
+		if restoreErr := os.Rename(previous, fullDest); restoreErr != nil {
+			keepWork = true
+			return fmt.Errorf("failed to publish extracted content: %w; previous content left at %s: %v", err, previous, restoreErr)
+		}
+		return fmt.Errorf("failed to publish extracted content: %w", err)
+	}
+	return nil
+}
+
+// stagingDir returns the staging directory inside BaseDir, creating it if needed.
+func (s *LocalStorage) stagingDir() (string, error) {
+	return ensureStagingDir(s.BaseDir)
+}
+
+// Stat describes a file or directory below BaseDir.
+func (s *LocalStorage) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	return os.Stat(s.path(name))
+}
+
+// Open opens a file below BaseDir. The returned *os.File supports seeking.
+func (s *LocalStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(s.path(name))
+}
+
+// List walks prefix, following symlinks, and returns the storage names of its files.
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	root := s.path(prefix)
+
+	err := walkFiles(root, func(name, _ string) error {
+		names = append(names, path.Join(strings.Trim(filepath.ToSlash(prefix), "/"), name))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// RemoveAll deletes a file or directory below BaseDir.
+func (s *LocalStorage) RemoveAll(ctx context.Context, name string) error {
+	return os.RemoveAll(s.path(name))
+}
+
+// path maps a storage name to its location on disk.
+func (s *LocalStorage) path(name string) string {
+	return filepath.Join(s.BaseDir, filepath.FromSlash(strings.TrimPrefix(name, "/")))
+}
+
+// copyTree copies a file or directory tree, keeping symlinks, for moves across filesystems.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		return copyFile(p, target)
+	})
+}
+
+// copyFile copies a single regular file, creating its parent directories.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// sniffHeadLen is how much of each file is kept for MIME detection, mimetype's default read limit.
+const sniffHeadLen = 3072
+
+// FileEntry describes a file of an extracted deployment.
+type FileEntry struct {
+	Path        string // Slash-separated with a leading slash, e.g. "/assets/app.js"
+	Size        int64
+	Mode        fs.FileMode // Permission bits
+	SHA256      string      // Hex-encoded digest of the content
+	ContentType string
+}
+
+// fileDigest is what is learned about a file while it is written.
+type fileDigest struct {
+	sha256 string
+	head   []byte
+}
+
+// extraction tracks one archive extraction: its resource budget and the digests of written files.
+type extraction struct {
+	*extractBudget
+	digests map[string]fileDigest // Keyed by path on disk
+}
+
+func newExtraction(budget *extractBudget) *extraction {
+	return &extraction{extractBudget: budget, digests: map[string]fileDigest{}}
+}
+
+// copy streams an entry to dst within the budget, hashing it on the way.
+func (x *extraction) copy(dst *os.File, src io.Reader, name string) error {
+	hash := sha256.New()
+	head := &headWriter{limit: sniffHeadLen}
+	if err := x.extractBudget.copy(io.MultiWriter(dst, hash, head), src, name); err != nil {
+		return err
+	}
+	x.digests[dst.Name()] = fileDigest{sha256: hex.EncodeToString(hash.Sum(nil)), head: head.buf}
+	return nil
+}
+
+// manifest lists every file served from root, following symlinks. Digests recorded during
+// extraction are reused; files reached another way, such as hardlinks, are hashed from disk.
+func (x *extraction) manifest(root string) ([]FileEntry, error) {
+	var files []FileEntry
+	err := walkFiles(root, func(name, p string) error {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+
+		digest, ok := x.digests[p]
+		if !ok {
+			if digest, err = digestFile(p); err != nil {
+				return err
+			}
+		}
+
+		files = append(files, FileEntry{
+			Path:        "/" + name,
+			Size:        info.Size(),
+			Mode:        info.Mode().Perm(),
+			SHA256:      digest.sha256,
+			ContentType: contentType(name, digest.head),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build file manifest: %w", err)
+	}
+	return files, nil
+}
+
+// digestFile hashes a file on disk.
+func digestFile(p string) (fileDigest, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return fileDigest{}, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	head := &headWriter{limit: sniffHeadLen}
+	if _, err := io.Copy(io.MultiWriter(hash, head), f); err != nil {
+		return fileDigest{}, err
+	}
+	return fileDigest{sha256: hex.EncodeToString(hash.Sum(nil)), head: head.buf}, nil
+}
+
+// contentType prefers the type registered for the file extension, as web servers do, and falls back
+// to detecting it from the content.
+func contentType(name string, head []byte) string {
+	if byExtension := mime.TypeByExtension(path.Ext(name)); byExtension != "" {
+		return byExtension
+	}
+	return mimetype.Detect(head).String()
+}
+
+// headWriter keeps the first limit bytes written to it.
+type headWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.buf); room > 0 {
+		w.buf = append(w.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mirror copies the files below prefix from storage to the same path under localDir, for servers such
+// as nginx that can only read local files. Complete mirrors are kept, so calling it again is cheap.
+// With local storage rooted at localDir the files are already in place and nothing is copied.
+func (e *FileEngine) Mirror(ctx context.Context, prefix, localDir string) error {
+	if e.servesFrom(localDir) {
+		return nil
+	}
+
+	target := filepath.Join(localDir, filepath.FromSlash(prefix))
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+
+	names, err := e.storage.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	// Download into the staging directory first so a partial mirror is never served
+	staging, err := ensureStagingDir(localDir)
+	if err != nil {
+		return fmt.Errorf("failed to create mirror staging directory: %w", err)
+	}
+	work, err := os.MkdirTemp(staging, "mirror-*")
+	if err != nil {
+		return fmt.Errorf("failed to create mirror staging directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	content := filepath.Join(work, "content")
+	if err := os.MkdirAll(content, 0755); err != nil {
+		return fmt.Errorf("failed to create mirror staging directory: %w", err)
+	}
+	for _, name := range names {
+		rel := strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+		if err := e.mirrorFile(ctx, name, filepath.Join(content, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	if err := os.Rename(content, target); err != nil {
+		return fmt.Errorf("failed to publish mirror: %w", err)
+	}
+	return nil
+}
+
+// RemoveMirror deletes the local copy of prefix made by Mirror.
+func (e *FileEngine) RemoveMirror(ctx context.Context, prefix, localDir string) error {
+	if e.servesFrom(localDir) {
+		return nil
+	}
+	return os.RemoveAll(filepath.Join(localDir, filepath.FromSlash(prefix)))
+}
+
+// mirrorFile downloads one stored file to a local path.
+func (e *FileEngine) mirrorFile(ctx context.Context, name, dst string) error {
+	body, err := e.storage.Open(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create mirror file: %w", err)
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write mirror file: %w", err)
+	}
+	return out.Close()
+}
+
+// servesFrom reports whether storage is the local directory itself.
+func (e *FileEngine) servesFrom(localDir string) bool {
+	local, ok := e.storage.(*LocalStorage)
+	if !ok {
+		return false
+	}
+	a, errA := filepath.Abs(local.BaseDir)
+	b, errB := filepath.Abs(localDir)
+	return errA == nil && errB == nil && a == b
+}
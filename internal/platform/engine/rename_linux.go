@@ -0,0 +1,9 @@
+package engine
+
+import "golang.org/x/sys/unix"
+
+// exchange atomically swaps two paths on the same filesystem, so neither is ever missing.
+// It fails on filesystems without RENAME_EXCHANGE support.
+func exchange(a, b string) error {
+	return unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package engine
+
+import "errors"
+
+// exchange atomically swaps two paths on the same filesystem. Only Linux supports it.
+func exchange(a, b string) error {
+	return errors.ErrUnsupported
+}
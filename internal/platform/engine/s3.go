@@ -0,0 +1,482 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// unsignedPayload skips payload hashing in SigV4 so uploads can stream from disk.
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3DeleteBatch is the most keys a single DeleteObjects request accepts.
+	s3DeleteBatch = 1000
+	// s3UploadConcurrency bounds the parallel PUTs of an import.
+	s3UploadConcurrency = 8
+)
+
+// S3Config configures an S3-compatible object storage such as AWS S3 or MinIO.
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // Address the bucket as {endpoint}/{bucket} instead of {bucket}.{endpoint}, as MinIO expects
+}
+
+// S3Storage keeps files as objects in an S3-compatible bucket, signing requests with AWS Signature V4.
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+// NewS3Storage creates a storage backed by an S3-compatible bucket.
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		pathStyle: cfg.PathStyle,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// Import uploads every file below dir as an object under dest, then removes dir. Symlinks are
+// uploaded as copies of what they point to.
+func (s *S3Storage) Import(ctx context.Context, dest, dir string) error {
+	type upload struct{ key, file string }
+	var uploads []upload
+	err := walkFiles(dir, func(name, file string) error {
+		uploads = append(uploads, upload{key: path.Join(dest, name), file: file})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read import directory: %w", err)
+	}
+
+	sem := make(chan struct{}, s3UploadConcurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for _, u := range uploads {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := s.putFile(ctx, u.key, u.file); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return os.RemoveAll(dir)
+}
+
+// putFile uploads a local file as an object.
+func (s *S3Storage) putFile(ctx context.Context, key, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Stat describes an object, or an implicit directory when objects exist below name.
+func (s *S3Storage) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	key := strings.Trim(name, "/")
+
+	if key != "" {
+		req, err := s.newRequest(ctx, http.MethodHead, key, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req)
+		if err == nil {
+			resp.Body.Close()
+			size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+			modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+			return fileInfo{name: key, size: size, modTime: modTime}, nil
+		}
+		if err != fs.ErrNotExist {
+			return nil, err
+		}
+	}
+
+	page, err := s.listPage(ctx, dirPrefix(key), "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Contents) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	return fileInfo{name: key, dir: true}, nil
+}
+
+// Open streams an object's content.
+func (s *S3Storage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.OpenAt(ctx, name, 0)
+}
+
+// OpenAt streams an object's content from offset with a ranged GET.
+func (s *S3Storage) OpenAt(ctx context.Context, name string, offset int64) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, strings.Trim(name, "/"), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// List returns the keys of every object below prefix.
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		page, err := s.listPage(ctx, dirPrefix(strings.Trim(prefix, "/")), token, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			names = append(names, object.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// RemoveAll deletes the object at name and every object below it, in batches.
+func (s *S3Storage) RemoveAll(ctx context.Context, name string) error {
+	key := strings.Trim(name, "/")
+	keys, err := s.List(ctx, key)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		keys = append(keys, key)
+	}
+
+	for start := 0; start < len(keys); start += s3DeleteBatch {
+		end := min(start+s3DeleteBatch, len(keys))
+		if err := s.deleteObjects(ctx, keys[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// listPage fetches one page of ListObjectsV2 results.
+func (s *S3Storage) listPage(ctx context.Context, prefix, token string, maxKeys int) (*s3ListResult, error) {
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {prefix},
+		"max-keys":  {strconv.Itoa(maxKeys)},
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+
+	req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+	defer resp.Body.Close()
+
+	var result s3ListResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode object list: %w", err)
+	}
+	return &result, nil
+}
+
+type s3DeleteRequest struct {
+	XMLName xml.Name `xml:"Delete"`
+	Quiet   bool     `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+type s3DeleteResult struct {
+	Errors []struct {
+		Key     string `xml:"Key"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+// deleteObjects removes up to s3DeleteBatch keys with one DeleteObjects request.
+func (s *S3Storage) deleteObjects(ctx context.Context, keys []string) error {
+	body := s3DeleteRequest{Quiet: true}
+	for _, key := range keys {
+		body.Objects = append(body.Objects, struct {
+			Key string `xml:"Key"`
+		}{Key: key})
+	}
+	payload, err := xml.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPost, "", url.Values{"delete": {""}}, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(payload)
+	req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	req.Header.Set("Content-Type", "application/xml")
+	req.ContentLength = int64(len(payload))
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete objects: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result s3DeleteResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode delete result: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		e := result.Errors[0]
+		return fmt.Errorf("failed to delete %s: %s: %s", e.Key, e.Code, e.Message)
+	}
+	return nil
+}
+
+// newRequest builds an unsigned request for an object key, or for the bucket when key is empty.
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	objectPath := "/" + key
+	if s.pathStyle {
+		objectPath = "/" + s.bucket + objectPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = objectPath
+	u.RawPath = s3Escape(objectPath, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	return req, nil
+}
+
+// do signs and sends a request, mapping 404 to fs.ErrNotExist and other failures to errors.
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fs.ErrNotExist
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+		return nil, fmt.Errorf("S3 %s %s: %s %s %s", req.Method, req.URL.Path, resp.Status, e.Code, e.Message)
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to a request, leaving its payload unsigned.
+func (s *S3Storage) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	scope, signature := s.signature(canonicalRequest(req, signedHeaders, unsignedPayload), amzDate)
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, strings.Join(signedHeaders, ";"), signature,
+	))
+}
+
+// canonicalRequest builds the SigV4 canonical request of req. signedHeaders are lowercase and sorted;
+// the path and query must already be escaped as S3 expects, as newRequest does.
+func canonicalRequest(req *http.Request, signedHeaders []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// signature signs a canonical request made at amzDate with the storage's secret key, returning the
+// credential scope and the hex-encoded signature.
+func (s *S3Storage) signature(canonicalRequest, amzDate string) (scope, signature string) {
+	date := amzDate[:8]
+	scope = date + "/" + s.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name with SigV4's strict escaping.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, keeping "/" unless encodeSlash is set.
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// dirPrefix turns a storage name into the key prefix of the objects below it.
+func dirPrefix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return name + "/"
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stagingDirName is the directory, inside storage and mirror directories, where content is prepared
+// before being published with a single rename. Anything left in it comes from an interrupted run.
+const stagingDirName = ".staging"
+
+// ensureStagingDir creates the staging directory inside dir.
+func ensureStagingDir(dir string) (string, error) {
+	staging := filepath.Join(dir, stagingDirName)
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return "", err
+	}
+	return staging, nil
+}
+
+// SweepStaging removes staging entries older than maxAge left behind by interrupted extractions,
+// imports and mirrors, in the engine's staging directory and in each mirror directory. Entries
+// younger than maxAge may belong to work still in progress and are kept. It returns how many
+// entries were removed.
+func (e *FileEngine) SweepStaging(maxAge time.Duration, mirrorDirs ...string) (int, error) {
+	staging, err := e.stagingDir()
+	if err != nil {
+		return 0, err
+	}
+	dirs := []string{staging}
+	for _, dir := range mirrorDirs {
+		if !e.servesFrom(dir) {
+			dirs = append(dirs, filepath.Join(dir, stagingDirName))
+		}
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	var errs []error
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, entry := range entries {
+			// Resumable and delta uploads outlive maxAge while idle and are removed when their session
+			// expires, along with their deployment
+			if strings.HasPrefix(entry.Name(), uploadPrefix) || strings.HasPrefix(entry.Name(), assemblyPrefix) {
+				continue
+			}
+			n, err := removeIfStale(filepath.Join(dir, entry.Name()), cutoff)
+			removed += n
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// Extractions before staging directories existed left {deployment}_extract_tmp siblings
+	if local, ok := e.storage.(*LocalStorage); ok {
+		legacy, _ := filepath.Glob(filepath.Join(local.BaseDir, "deployments", "*", "*_extract_tmp"))
+		for _, path := range legacy {
+			n, err := removeIfStale(path, cutoff)
+			removed += n
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// removeIfStale removes path when it was last modified before cutoff, returning 1 if it did.
+func removeIfStale(path string, cutoff time.Time) (int, error) {
+	info, err := os.Lstat(path)
+	if err != nil || !info.ModTime().Before(cutoff) {
+		return 0, nil
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
@@ -0,0 +1,230 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+)
+
+// Storage holds deployment files. Names are slash-separated paths relative to the storage root,
+// e.g. "deployments/{projectId}/{deploymentId}/index.html". Directories are implicit: one exists
+// as long as a file exists below it.
+type Storage interface {
+	// Import moves the contents of a local directory to dest. The local directory is consumed.
+	Import(ctx context.Context, dest, dir string) error
+	// Stat describes a file or directory, returning fs.ErrNotExist when there is neither.
+	Stat(ctx context.Context, name string) (fs.FileInfo, error)
+	// Open reads a file, returning fs.ErrNotExist when it does not exist.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of every file below prefix, recursively and in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// RemoveAll deletes name and everything below it. Missing names are not an error.
+	RemoveAll(ctx context.Context, name string) error
+}
+
+// rangeOpener is implemented by storages that can read a file from an offset without transferring
+// what precedes it, letting open files seek.
+type rangeOpener interface {
+	// OpenAt reads a file from offset to its end, returning fs.ErrNotExist when it does not exist.
+	OpenAt(ctx context.Context, name string, offset int64) (io.ReadCloser, error)
+}
+
+// fileInfo is the fs.FileInfo of stored files and implicit directories.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i fileInfo) Name() string       { return path.Base(i.name) }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.dir }
+func (i fileInfo) Sys() any           { return nil }
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+const (
+	// missingTTL is how long a storageFS remembers that a name does not exist. Files of a deployment
+	// are all imported before it is served, so a name missing from one stays missing.
+	missingTTL = time.Minute
+	// missingMax bounds the number of missing names remembered at once.
+	missingMax = 10000
+)
+
+// storageFS adapts a Storage to fs.FS for readers such as the static gateway. Names found missing
+// are remembered, so repeated lookups of absent files, e.g. precompressed variants, stay local.
+type storageFS struct {
+	storage Storage
+
+	mu      sync.Mutex
+	missing map[string]time.Time // Name to expiry
+}
+
+func newStorageFS(storage Storage) *storageFS {
+	return &storageFS{storage: storage, missing: map[string]time.Time{}}
+}
+
+// Open implements fs.FS. Directories can be opened for Stat but not read.
+func (s *storageFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &storageFile{info: info}, nil
+	}
+
+	// Seekable files open their content on first read, from wherever the reader seeked to
+	if opener, ok := s.storage.(rangeOpener); ok {
+		return &rangedFile{storageFile: storageFile{info: info}, opener: opener, name: name}, nil
+	}
+
+	body, err := s.storage.Open(context.Background(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.remember(name)
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if file, ok := body.(fs.File); ok {
+		return file, nil
+	}
+	return &storageFile{info: info, body: body}, nil
+}
+
+// Stat implements fs.StatFS so callers can stat without opening.
+func (s *storageFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if s.known(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+
+	info, err := s.storage.Stat(context.Background(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.remember(name)
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// known reports whether a name was found missing within missingTTL.
+func (s *storageFS) known(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry, ok := s.missing[name]
+	if ok && time.Now().After(expiry) {
+		delete(s.missing, name)
+		return false
+	}
+	return ok
+}
+
+// remember records a missing name, evicting expired entries, or all of them, when full.
+func (s *storageFS) remember(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if len(s.missing) >= missingMax {
+		for n, expiry := range s.missing {
+			if now.After(expiry) {
+				delete(s.missing, n)
+			}
+		}
+		if len(s.missing) >= missingMax {
+			clear(s.missing)
+		}
+	}
+	s.missing[name] = now.Add(missingTTL)
+}
+
+// storageFile is an open file of a storage without native fs.File support.
+type storageFile struct {
+	info fs.FileInfo
+	body io.ReadCloser // nil for directories
+}
+
+func (f *storageFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *storageFile) Read(p []byte) (int, error) {
+	if f.body == nil {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: errors.New("is a directory")}
+	}
+	return f.body.Read(p)
+}
+
+func (f *storageFile) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	return f.body.Close()
+}
+
+// rangedFile is an open file of a storage with ranged reads. It implements io.Seeker by reopening
+// its content at the new offset, so serving a range never transfers the bytes before it.
+type rangedFile struct {
+	storageFile
+	opener rangeOpener
+	name   string
+	offset int64
+}
+
+func (f *rangedFile) Read(p []byte) (int, error) {
+	if f.offset >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		body, err := f.opener.OpenAt(context.Background(), f.name, f.offset)
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+		}
+		f.body = body
+	}
+
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *rangedFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
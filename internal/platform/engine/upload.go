@@ -0,0 +1,349 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// uploadPrefix names the staging directories of resumable uploads. They live as long as their
+	// session and are removed through RemoveUpload rather than by the staging sweep.
+	uploadPrefix = "upload-"
+	// uploadDataName and uploadStateName are the received bytes and their state inside an upload.
+	uploadDataName  = "data"
+	uploadStateName = "state.json"
+)
+
+var (
+	// ErrUploadNotFound is returned for an upload never created, already completed or removed.
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadBusy is returned while another request writes to or completes the same upload.
+	ErrUploadBusy = errors.New("upload is busy with another request")
+	// ErrChunkTooLarge is returned for a chunk reaching past the upload's declared size.
+	ErrChunkTooLarge = errors.New("chunk extends past the declared upload size")
+	// ErrChecksumMismatch is returned when a completed upload does not match its checksum.
+	ErrChecksumMismatch = errors.New("uploaded archive does not match its checksum")
+)
+
+// OffsetError reports a chunk sent for an offset other than the upload's current one.
+type OffsetError struct {
+	Offset int64 // Bytes received so far, where the next chunk must start
+}
+
+func (e *OffsetError) Error() string {
+	return fmt.Sprintf("chunk must start at offset %d", e.Offset)
+}
+
+// IncompleteUploadError reports an upload completed before all of its bytes were received.
+type IncompleteUploadError struct {
+	Offset int64
+	Size   int64
+}
+
+func (e *IncompleteUploadError) Error() string {
+	return fmt.Sprintf("received %d of %d bytes", e.Offset, e.Size)
+}
+
+// uploadState is what has been committed of an upload. Bytes of the data file past Offset come
+// from an interrupted write and are discarded.
+type uploadState struct {
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+	SHA256 []byte `json:"sha256"` // Serialized hash of the first Offset bytes
+}
+
+// CheckUpload validates the declared size of an archive against the engine's limits before any of
+// it is sent.
+func (e *FileEngine) CheckUpload(size int64) error {
+	if e.limits.MaxArchiveBytes > 0 && size > e.limits.MaxArchiveBytes {
+		return &LimitError{Limit: "archive_bytes", Detail: fmt.Sprintf("larger than %d bytes", e.limits.MaxArchiveBytes)}
+	}
+	return nil
+}
+
+// CreateUpload starts a resumable upload of an archive of size bytes in the staging directory.
+func (e *FileEngine) CreateUpload(ctx context.Context, id string, size int64) error {
+	if err := e.CheckUpload(size); err != nil {
+		return err
+	}
+	dir, err := e.uploadDir(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, uploadDataName), nil, 0644); err != nil {
+		return fmt.Errorf("failed to create upload file: %w", err)
+	}
+	hash, err := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return writeUploadState(dir, uploadState{Size: size, SHA256: hash})
+}
+
+// UploadOffset returns how many bytes of an upload have been received.
+func (e *FileEngine) UploadOffset(ctx context.Context, id string) (int64, error) {
+	dir, err := e.uploadDir(id)
+	if err != nil {
+		return 0, err
+	}
+	state, err := readUploadState(dir)
+	if err != nil {
+		return 0, err
+	}
+	return state.Offset, nil
+}
+
+// WriteChunk appends chunk to an upload at offset, which must be the number of bytes received so
+// far. Bytes received before a failed read are kept, so the client resumes from the returned offset.
+func (e *FileEngine) WriteChunk(ctx context.Context, id string, offset int64, chunk io.Reader) (int64, error) {
+	unlock, err := e.lockUpload(id)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	dir, err := e.uploadDir(id)
+	if err != nil {
+		return 0, err
+	}
+	state, err := readUploadState(dir)
+	if err != nil {
+		return 0, err
+	}
+	if offset != state.Offset {
+		return state.Offset, &OffsetError{Offset: state.Offset}
+	}
+
+	hash := sha256.New()
+	if err := hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state.SHA256); err != nil {
+		return state.Offset, fmt.Errorf("failed to restore upload checksum: %w", err)
+	}
+
+	data, err := os.OpenFile(filepath.Join(dir, uploadDataName), os.O_WRONLY, 0)
+	if err != nil {
+		return state.Offset, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer data.Close()
+	if err := data.Truncate(state.Offset); err != nil {
+		return state.Offset, fmt.Errorf("failed to resume upload: %w", err)
+	}
+	if _, err := data.Seek(state.Offset, io.SeekStart); err != nil {
+		return state.Offset, fmt.Errorf("failed to resume upload: %w", err)
+	}
+
+	remaining := state.Size - state.Offset
+	n, copyErr := io.Copy(io.MultiWriter(data, hash), io.LimitReader(chunk, remaining))
+	if copyErr == nil && n == remaining {
+		var extra [1]byte
+		if k, _ := io.ReadFull(chunk, extra[:]); k > 0 {
+			// Reject the whole chunk; its bytes past the committed offset are truncated next time
+			return state.Offset, ErrChunkTooLarge
+		}
+	}
+
+	if err := data.Sync(); err != nil {
+		return state.Offset, fmt.Errorf("failed to write upload file: %w", err)
+	}
+	sum, err := hash.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return state.Offset, err
+	}
+	state.Offset += n
+	state.SHA256 = sum
+	if err := writeUploadState(dir, state); err != nil {
+		return state.Offset - n, err
+	}
+
+	if copyErr != nil {
+		return state.Offset, fmt.Errorf("failed to receive chunk: %w", copyErr)
+	}
+	return state.Offset, nil
+}
+
+// CompleteUpload verifies a fully received upload against its hex-encoded SHA-256 checksum and hands
+// over the archive. The upload is gone afterwards; closing the archive deletes it.
+func (e *FileEngine) CompleteUpload(ctx context.Context, id, checksum string) (io.ReadCloser, error) {
+	unlock, err := e.lockUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	defer e.uploads.Delete(id)
+
+	dir, err := e.uploadDir(id)
+	if err != nil {
+		return nil, err
+	}
+	state, err := readUploadState(dir)
+	if err != nil {
+		return nil, err
+	}
+	if state.Offset != state.Size {
+		return nil, &IncompleteUploadError{Offset: state.Offset, Size: state.Size}
+	}
+
+	hash := sha256.New()
+	if err := hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state.SHA256); err != nil {
+		return nil, fmt.Errorf("failed to restore upload checksum: %w", err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != strings.ToLower(checksum) {
+		return nil, ErrChecksumMismatch
+	}
+
+	// Move the archive to a regular staging entry, swept like any other if the caller never closes it
+	staging, err := e.stagingDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	work, err := os.MkdirTemp(staging, "archive-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	archive := filepath.Join(work, uploadDataName)
+	if err := os.Rename(filepath.Join(dir, uploadDataName), archive); err != nil {
+		os.RemoveAll(work)
+		return nil, fmt.Errorf("failed to move upload file: %w", err)
+	}
+	if err := os.Truncate(archive, state.Size); err != nil {
+		os.RemoveAll(work)
+		return nil, fmt.Errorf("failed to move upload file: %w", err)
+	}
+	os.RemoveAll(dir)
+
+	f, err := os.Open(archive)
+	if err != nil {
+		os.RemoveAll(work)
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	return &stagedFile{File: f, dir: work}, nil
+}
+
+// RemoveUpload deletes an upload and the bytes received for it. Missing uploads are not an error.
+func (e *FileEngine) RemoveUpload(ctx context.Context, id string) error {
+	unlock, err := e.lockUpload(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer e.uploads.Delete(id)
+
+	dir, err := e.uploadDir(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// StaleUploads returns the IDs of uploads that received nothing for maxAge, such as those whose
+// session was lost. Remove them with RemoveUpload.
+func (e *FileEngine) StaleUploads(maxAge time.Duration) ([]string, error) {
+	staging, err := e.stagingDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var ids []string
+	for _, entry := range entries {
+		id, ok := strings.CutPrefix(entry.Name(), uploadPrefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		// The state is rewritten with every chunk, so its age is the upload's idle time. Uploads
+		// interrupted while being created have none and age with their directory.
+		info, err := os.Stat(filepath.Join(staging, entry.Name(), uploadStateName))
+		if errors.Is(err, fs.ErrNotExist) {
+			info, err = entry.Info()
+		}
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// uploadDir returns the staging directory of the upload id.
+func (e *FileEngine) uploadDir(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", fmt.Errorf("invalid upload id %q", id)
+	}
+	staging, err := e.stagingDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	return filepath.Join(staging, uploadPrefix+id), nil
+}
+
+// lockUpload takes the lock of an upload without waiting, returning ErrUploadBusy when it is held.
+func (e *FileEngine) lockUpload(id string) (func(), error) {
+	mu, _ := e.uploads.LoadOrStore(id, &sync.Mutex{})
+	if !mu.(*sync.Mutex).TryLock() {
+		return nil, ErrUploadBusy
+	}
+	return mu.(*sync.Mutex).Unlock, nil
+}
+
+// readUploadState reads the committed state of the upload in dir.
+func readUploadState(dir string) (uploadState, error) {
+	var state uploadState
+	data, err := os.ReadFile(filepath.Join(dir, uploadStateName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, ErrUploadNotFound
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read upload state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to read upload state: %w", err)
+	}
+	return state, nil
+}
+
+// writeUploadState commits the state of the upload in dir with a rename, so it is never torn.
+func writeUploadState(dir string, state uploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, uploadStateName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, uploadStateName)); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	return nil
+}
+
+// stagedFile is a file in its own staging directory, deleted with the directory on Close.
+type stagedFile struct {
+	*os.File
+	dir string
+}
+
+func (f *stagedFile) Close() error {
+	err := f.File.Close()
+	os.RemoveAll(f.dir)
+	return err
+}
@@ -0,0 +1,331 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// patchMaxFileSize is the largest file, on either side, a unified diff is produced for.
+	patchMaxFileSize = 64 << 10
+	// patchMaxFiles bounds how many files of one comparison get a unified diff.
+	patchMaxFiles = 100
+	// patchMaxEdits bounds the lines added plus removed in one file before its diff is given up on.
+	patchMaxEdits = 1000
+	// patchContext is the number of unchanged lines shown around each change.
+	patchContext = 3
+)
+
+// ErrFilesNotRecorded is returned when comparing a deployment whose files were never recorded, such
+// as a proxy deployment or one still being extracted.
+var ErrFilesNotRecorded = errors.New("deployment has no recorded files")
+
+// compareFiles diffs the manifest of a deployment against an older one.
+func compareFiles(oldFiles, newFiles []DeploymentFile) DeploymentDiff {
+	diff := DeploymentDiff{
+		Added:    make([]FileChange, 0),
+		Removed:  make([]FileChange, 0),
+		Modified: make([]FileChange, 0),
+	}
+
+	old := make(map[string]DeploymentFile, len(oldFiles))
+	for _, f := range oldFiles {
+		old[f.Path] = f
+		diff.Totals.OldSize += f.Size
+	}
+
+	for _, f := range newFiles {
+		diff.Totals.NewSize += f.Size
+
+		prev, ok := old[f.Path]
+		if !ok {
+			diff.Added = append(diff.Added, FileChange{
+				Path:        f.Path,
+				NewSize:     &f.Size,
+				SizeDelta:   f.Size,
+				NewMode:     f.Mode,
+				NewSHA256:   f.SHA256,
+				ContentType: f.ContentType,
+			})
+			continue
+		}
+		delete(old, f.Path)
+
+		if prev.SHA256 == f.SHA256 && prev.Mode == f.Mode {
+			diff.Totals.Unchanged++
+			continue
+		}
+		diff.Modified = append(diff.Modified, FileChange{
+			Path:        f.Path,
+			OldSize:     &prev.Size,
+			NewSize:     &f.Size,
+			SizeDelta:   f.Size - prev.Size,
+			OldMode:     prev.Mode,
+			NewMode:     f.Mode,
+			OldSHA256:   prev.SHA256,
+			NewSHA256:   f.SHA256,
+			ContentType: f.ContentType,
+		})
+	}
+
+	for _, f := range old {
+		diff.Removed = append(diff.Removed, FileChange{
+			Path:        f.Path,
+			OldSize:     &f.Size,
+			SizeDelta:   -f.Size,
+			OldMode:     f.Mode,
+			OldSHA256:   f.SHA256,
+			ContentType: f.ContentType,
+		})
+	}
+
+	for _, changes := range [][]FileChange{diff.Added, diff.Removed, diff.Modified} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	}
+
+	diff.Totals.Added = len(diff.Added)
+	diff.Totals.Removed = len(diff.Removed)
+	diff.Totals.Modified = len(diff.Modified)
+	diff.Totals.SizeDelta = diff.Totals.NewSize - diff.Totals.OldSize
+	return diff
+}
+
+// addPatches fills in unified diffs for the small text files of a comparison. Files whose content is
+// no longer stored, such as those of expired deployments, are left without one.
+func (s *Service) addPatches(ctx context.Context, diff *DeploymentDiff, oldDep, newDep *Deployment) error {
+	oldRoot := "deployments/" + oldDep.ProjectID + "/" + oldDep.ID
+	newRoot := "deployments/" + newDep.ProjectID + "/" + newDep.ID
+	oldStored := oldDep.Status != StatusExpired
+	newStored := newDep.Status != StatusExpired
+
+	patched := 0
+	patch := func(c *FileChange) error {
+		if patched >= patchMaxFiles {
+			return nil
+		}
+		if (c.OldSize != nil && (!oldStored || *c.OldSize > patchMaxFileSize)) ||
+			(c.NewSize != nil && (!newStored || *c.NewSize > patchMaxFileSize)) {
+			return nil
+		}
+
+		var oldText, newText []byte
+		var err error
+		if c.OldSize != nil {
+			if oldText, err = s.readFile(ctx, oldRoot+c.Path); err != nil {
+				return err
+			}
+		}
+		if c.NewSize != nil {
+			if newText, err = s.readFile(ctx, newRoot+c.Path); err != nil {
+				return err
+			}
+		}
+		if !isText(oldText) || !isText(newText) {
+			return nil
+		}
+
+		oldName, newName := "a"+c.Path, "b"+c.Path
+		if c.OldSize == nil {
+			oldName = "/dev/null"
+		}
+		if c.NewSize == nil {
+			newName = "/dev/null"
+		}
+		c.Patch = unifiedDiff(oldName, newName, string(oldText), string(newText))
+		if c.Patch != "" {
+			patched++
+		}
+		return nil
+	}
+
+	for _, changes := range [][]FileChange{diff.Modified, diff.Added, diff.Removed} {
+		for i := range changes {
+			if err := patch(&changes[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readFile reads a stored file of at most patchMaxFileSize bytes.
+func (s *Service) readFile(ctx context.Context, name string) ([]byte, error) {
+	f, err := s.fileEngine.Open(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, patchMaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// isText reports whether content can be shown in a text diff.
+func isText(content []byte) bool {
+	return len(content) <= patchMaxFileSize && utf8.Valid(content) && bytes.IndexByte(content, 0) < 0
+}
+
+// lineEdit is one line of an edit script: ' ' for a kept line, '-' removed or '+' added.
+type lineEdit struct {
+	op   byte
+	line string
+}
+
+// unifiedDiff renders the changes from oldText to newText as a unified diff. It returns an empty
+// string when the texts are equal or differ in more than patchMaxEdits lines.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	edits, ok := diffLines(splitLines(oldText), splitLines(newText), patchMaxEdits)
+	if !ok {
+		return ""
+	}
+
+	// Position of each edit in both texts, counted in lines before it
+	oldPos := make([]int, len(edits)+1)
+	newPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if e.op != '+' {
+			oldPos[i+1]++
+		}
+		if e.op != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		// Grow the hunk while the next change is close enough for their context to touch
+		start := max(0, i-patchContext)
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*patchContext {
+				break
+			}
+		}
+		end = min(len(edits), end+patchContext)
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(oldPos[start], oldPos[end]-oldPos[start]),
+			hunkRange(newPos[start], newPos[end]-newPos[start]))
+		for _, e := range edits[start:end] {
+			b.WriteByte(e.op)
+			b.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the line range of a hunk, where start is the number of lines before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits text into lines that keep their newline, so a missing final newline is a change.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script from a to b with Myers' algorithm. It gives up, returning
+// false, when more than maxEdits lines must be added or removed.
+func diffLines(a, b []string, maxEdits int) ([]lineEdit, bool) {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	// trace[d] holds the furthest x reached on each diagonal k = x - y with d-1 edits
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// backtrack walks the trace of diffLines back from the end of both texts to build the edit script.
+func backtrack(a, b []string, trace [][]int, offset int) []lineEdit {
+	x, y := len(a), len(b)
+	var edits []lineEdit
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, lineEdit{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, lineEdit{'+', b[y-1]})
+			y--
+		} else {
+			edits = append(edits, lineEdit{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		edits = append(edits, lineEdit{' ', a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
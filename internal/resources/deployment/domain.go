@@ -2,8 +2,11 @@ package deployment
 
 import (
 	"context"
+	"io"
 	"time"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
 	"github.com/dimasbaguspm/infario/pkgs/request"
 	"github.com/dimasbaguspm/infario/pkgs/response"
 )
@@ -14,8 +17,20 @@ const (
 	StatusError   = "error"
 	StatusExpired = "expired"
 
+	// TypeStatic deployments serve files extracted from an uploaded archive.
+	TypeStatic = "static"
+	// TypeProxy deployments forward every request to an upstream instead of serving files.
+	TypeProxy = "proxy"
+
 	// Redis queue key for deployment tasks
 	QueueKey = "deployments"
+
+	// UploadSessionTTL is how long a resumable upload may go without receiving a chunk before it
+	// is abandoned.
+	UploadSessionTTL = 24 * time.Hour
+	// DeltaUploadTTL is how long a delta upload may go without receiving a content before it is
+	// abandoned.
+	DeltaUploadTTL = 24 * time.Hour
 )
 
 // Deployment represents a single immutable build artifact.
@@ -23,14 +38,51 @@ const (
 // @Description Deployment entity representing a built artifact with content-addressable identifier
 // @Name Deployment
 type Deployment struct {
-	ID          string     `json:"id"`
-	ProjectID   string     `json:"project_id"`
-	Hash        string     `json:"hash"` // The content-addressable identifier
-	Status      string     `json:"status"`
-	CreatedAt   time.Time  `json:"created_at"`
-	ExpiredAt   *time.Time `json:"expired_at,omitempty"` // Nullable: some builds may never expire
-	ProjectName *string    `json:"project_name,omitempty"`
-	EntryPath   string     `json:"entry_path"` // URL path prefix for Traefik routing
+	ID            string                 `json:"id"`
+	ProjectID     string                 `json:"project_id"`
+	Hash          string                 `json:"hash"`       // The content-addressable identifier
+	PreviewID     string                 `json:"preview_id"` // Short DNS label unique within the project, used in the preview URL
+	Status        string                 `json:"status"`
+	CreatedAt     time.Time              `json:"created_at"`
+	ExpiredAt     *time.Time             `json:"expired_at,omitempty"` // Nullable: some builds may never expire
+	ProjectName   *string                `json:"project_name,omitempty"`
+	EntryPath     string                 `json:"entry_path"`               // Entry file, or directory when ending with "/"
+	ServingMode   *string                `json:"serving_mode,omitempty"`   // spa, static or clean_urls; resolved during processing
+	Redirects     []gateway.RedirectRule `json:"redirects"`                // Parsed from the archive's _redirects file
+	Headers       []gateway.HeaderRule   `json:"headers"`                  // Parsed from the archive's _headers file
+	Metadata      map[string]string      `json:"metadata"`                 // Free-form labels from infario.json
+	Type          string                 `json:"type"`                     // static or proxy
+	Upstreams     []gateway.Upstream     `json:"upstreams"`                // Path prefixes proxied to backends
+	TotalSize     *int64                 `json:"total_size,omitempty"`     // Bytes across all files, set once extracted
+	FileCount     *int                   `json:"file_count,omitempty"`     // Set once extracted
+	ErrorMessage  *string                `json:"error_message,omitempty"`  // Why processing failed when status is error
+	Production    bool                   `json:"production"`               // Served as its project's production deployment
+	URL           string                 `json:"url,omitempty"`            // Production URL when served as production, otherwise the preview URL
+	PreviewURL    string                 `json:"preview_url,omitempty"`    // URL serving this deployment under its preview ID
+	ProductionURL string                 `json:"production_url,omitempty"` // Set while this deployment is the project's production deployment
+	Protection    *DeploymentProtection  `json:"protection,omitempty"`     // Basic auth of the preview hostname, absent when it follows the project
+}
+
+// DeploymentProtection describes the basic auth users of a deployment without exposing password hashes.
+// @Description Basic auth users required on a deployment's preview hostname instead of its project's
+// @Name DeploymentProtection
+type DeploymentProtection struct {
+	Usernames []string `json:"usernames"`
+}
+
+// SetDeploymentProtection represents the payload for protecting a deployment's preview hostname with basic auth.
+// @Description Deployment basic auth DTO, replacing any existing users
+// @Name SetDeploymentProtection
+type SetDeploymentProtection struct {
+	ID          string               `json:"id" validate:"required,uuid4"`
+	Credentials []project.Credential `json:"credentials" validate:"required,min=1,max=20,unique=Username,dive"`
+}
+
+// RemoveDeploymentProtection represents the payload for returning a deployment to its project's protection.
+// @Description Deployment basic auth removal DTO
+// @Name RemoveDeploymentProtection
+type RemoveDeploymentProtection struct {
+	ID string `json:"id" validate:"required,uuid4"`
 }
 
 // DeploymentTask extends Deployment with temporary metadata for async file processing.
@@ -47,6 +99,15 @@ type GetSingleDeployment struct {
 	ID string `json:"id" validate:"required,uuid4"`
 }
 
+// GetRoutedDeployment represents the payload for resolving the deployment served on a hostname.
+// @Description Payload for fetching a ready deployment by project name and preview ID, or by hash when set
+// @Name GetRoutedDeployment
+type GetRoutedDeployment struct {
+	ProjectName string `json:"project_name" validate:"required"`
+	PreviewID   string `json:"preview_id" validate:"required"`
+	Hash        string `json:"hash,omitempty"` // Also matches deployments by hash, case-insensitively
+}
+
 // GetPagedDeployment represents pagination parameters for listing deployments.
 // @Description Pagination parameters for listing deployments with optional filters
 // @Name GetPagedDeployment
@@ -61,35 +122,255 @@ type GetPagedDeployment struct {
 // @Name DeploymentPaged
 type DeploymentPaged response.Collection[Deployment]
 
+// DeploymentFile describes one file of an extracted deployment.
+// @Description File of a deployment with its content digest
+// @Name DeploymentFile
+type DeploymentFile struct {
+	Path        string `json:"path"` // Request path of the file, e.g. "/assets/app.js"
+	Size        int64  `json:"size"`
+	Mode        string `json:"mode"`   // Octal permission bits, e.g. "0644"
+	SHA256      string `json:"sha256"` // Hex-encoded digest of the content
+	ContentType string `json:"content_type"`
+}
+
+// GetDeploymentFiles represents pagination parameters for listing a deployment's files.
+// @Description Pagination parameters for listing deployment files with an optional path prefix
+// @Name GetDeploymentFiles
+type GetDeploymentFiles struct {
+	request.PagingParams
+	ID     string `json:"id" validate:"required,uuid4"`
+	Prefix string `json:"prefix" validate:"omitempty,startswith=/,max=1024"` // Only files whose path starts with it, e.g. "/assets/"
+}
+
+// DeploymentFilePaged represents a paginated response of deployment files.
+// @Description Paginated deployment file response with metadata
+// @Name DeploymentFilePaged
+type DeploymentFilePaged response.Collection[DeploymentFile]
+
+// GetDeploymentDiff represents the payload for comparing a deployment with an earlier one.
+// @Description Payload for comparing the files of two deployments
+// @Name GetDeploymentDiff
+type GetDeploymentDiff struct {
+	ID      string `json:"id" validate:"required,uuid4"`
+	Against string `json:"against" validate:"required,uuid4,nefield=ID"` // Deployment compared against, usually the one in production
+	Patch   bool   `json:"patch"`                                        // Include unified diffs of small text files
+}
+
+// FileChange describes a file added, removed or modified between two deployments.
+// @Description File that differs between two deployments
+// @Name FileChange
+type FileChange struct {
+	Path        string `json:"path"`
+	OldSize     *int64 `json:"old_size,omitempty"` // Unset for added files
+	NewSize     *int64 `json:"new_size,omitempty"` // Unset for removed files
+	SizeDelta   int64  `json:"size_delta"`
+	OldMode     string `json:"old_mode,omitempty"`
+	NewMode     string `json:"new_mode,omitempty"`
+	OldSHA256   string `json:"old_sha256,omitempty"`
+	NewSHA256   string `json:"new_sha256,omitempty"`
+	ContentType string `json:"content_type"`
+	Patch       string `json:"patch,omitempty"` // Unified diff, only for text files up to 64 KiB when requested
+}
+
+// DiffTotals summarizes the differences between two deployments.
+// @Description Counts and sizes of a deployment comparison
+// @Name DiffTotals
+type DiffTotals struct {
+	Added     int   `json:"added"`
+	Removed   int   `json:"removed"`
+	Modified  int   `json:"modified"`
+	Unchanged int   `json:"unchanged"`
+	OldSize   int64 `json:"old_size"` // Bytes across all files of the deployment compared against
+	NewSize   int64 `json:"new_size"`
+	SizeDelta int64 `json:"size_delta"`
+}
+
+// DeploymentDiff lists the files that changed between two deployments.
+// @Description Files added, removed and modified by a deployment compared with another one
+// @Name DeploymentDiff
+type DeploymentDiff struct {
+	ID       string       `json:"id"`
+	Against  string       `json:"against"`
+	Added    []FileChange `json:"added"`
+	Removed  []FileChange `json:"removed"`
+	Modified []FileChange `json:"modified"` // Content or permissions changed
+	Totals   DiffTotals   `json:"totals"`
+}
+
 // UploadDeployment represents the payload for uploading a deployment artifact.
-// @Description Deployment upload DTO (zip or tar.gz with default 30-day TTL)
+// @Description Deployment upload DTO (zip or tar archive, optionally gzip, bzip2, zstd or xz compressed, with default 30-day TTL)
 // @Name UploadDeployment
 type UploadDeployment struct {
-	ProjectID string `json:"project_id" validate:"required,uuid4"`
-	Hash      string `json:"hash" validate:"required"` // Content-addressable identifier
-	EntryPath string `json:"entry_path" validate:"required"`
+	ProjectID   string `json:"project_id" validate:"required,uuid4"`
+	Hash        string `json:"hash" validate:"required,max=255"`                              // Content-addressable identifier
+	EntryPath   string `json:"entry_path" validate:"omitempty,startswith=/"`                  // Falls back to infario.json, then "/"
+	ServingMode string `json:"serving_mode" validate:"omitempty,oneof=spa static clean_urls"` // Falls back to infario.json, then the project setting
 	request.FileUpload
 }
 
+// DeltaFile is a file of a delta upload manifest.
+// @Description File of a deployment uploaded by content digest
+// @Name DeltaFile
+type DeltaFile struct {
+	Path   string `json:"path" validate:"required,startswith=/,max=1024"` // Request path of the file, e.g. "/assets/app.js"
+	SHA256 string `json:"sha256" validate:"required,len=64,hexadecimal,lowercase"`
+	Size   int64  `json:"size" validate:"min=0"`
+	Mode   string `json:"mode,omitempty" validate:"omitempty,oneof=0644 0755"` // Defaults to 0644
+}
+
+// CreateDeltaUpload represents the payload starting an upload of only the files the server lacks.
+// @Description Delta upload DTO listing every file of the deployment by content digest
+// @Name CreateDeltaUpload
+type CreateDeltaUpload struct {
+	ProjectID   string      `json:"project_id" validate:"required,uuid4"`
+	Hash        string      `json:"hash" validate:"required,max=255"`
+	EntryPath   string      `json:"entry_path" validate:"omitempty,startswith=/"`
+	ServingMode string      `json:"serving_mode" validate:"omitempty,oneof=spa static clean_urls"`
+	Files       []DeltaFile `json:"files" validate:"required,min=1,unique=Path,dive"`
+}
+
+// DeltaUpload is the state of a delta upload.
+// @Description Pending deployment and the contents still to be uploaded before finalizing it
+// @Name DeltaUpload
+type DeltaUpload struct {
+	Deployment *Deployment `json:"deployment"`
+	Missing    []string    `json:"missing"` // Digests to upload to /deployments/{id}/blobs/{sha256}
+}
+
+// UploadDeltaBlob represents the payload uploading one content of a delta upload.
+// @Description Content of a delta upload identified by its SHA-256 digest
+// @Name UploadDeltaBlob
+type UploadDeltaBlob struct {
+	ID      string    `json:"id" validate:"required,uuid4"`
+	SHA256  string    `json:"sha256" validate:"required,len=64,hexadecimal,lowercase"`
+	Content io.Reader `json:"-" validate:"required"`
+}
+
+// FinalizeDeltaUpload represents the payload completing a delta upload.
+// @Description Payload for assembling a delta upload once every content is uploaded
+// @Name FinalizeDeltaUpload
+type FinalizeDeltaUpload struct {
+	ID string `json:"id" validate:"required,uuid4"`
+}
+
+// CreateResumableUpload represents the payload starting an archive upload sent in chunks.
+// @Description Resumable upload DTO declaring the archive's size and SHA-256 checksum up front
+// @Name CreateResumableUpload
+type CreateResumableUpload struct {
+	ProjectID   string `json:"project_id" validate:"required,uuid4"`
+	Hash        string `json:"hash" validate:"required,max=255"`
+	EntryPath   string `json:"entry_path" validate:"omitempty,startswith=/"`
+	ServingMode string `json:"serving_mode" validate:"omitempty,oneof=spa static clean_urls"`
+	Filename    string `json:"filename" validate:"omitempty,max=255"` // Name of the archive, e.g. "site.tar.gz"
+	Size        int64  `json:"size" validate:"required,min=1"`        // Bytes of the archive
+	SHA256      string `json:"sha256" validate:"required,len=64,hexadecimal,lowercase"`
+}
+
+// ResumableUpload is the state of a resumable upload.
+// @Description Pending deployment and how much of its archive has been received
+// @Name ResumableUpload
+type ResumableUpload struct {
+	Deployment *Deployment `json:"deployment"`
+	Filename   string      `json:"filename"`
+	Size       int64       `json:"size"`
+	Offset     int64       `json:"offset"` // Bytes received so far, where the next chunk must start
+	SHA256     string      `json:"sha256"`
+	ExpiresAt  time.Time   `json:"expires_at"` // Abandoned unless a chunk is received before then
+}
+
+// GetResumableUpload represents the payload for retrieving the state of a resumable upload.
+// @Description Payload for fetching a resumable upload by its deployment ID
+// @Name GetResumableUpload
+type GetResumableUpload struct {
+	ID string `json:"id" validate:"required,uuid4"`
+}
+
+// UploadChunk represents the payload sending the next chunk of a resumable upload.
+// @Description Chunk of a resumable upload starting at the offset received so far
+// @Name UploadChunk
+type UploadChunk struct {
+	ID      string    `json:"id" validate:"required,uuid4"`
+	Offset  int64     `json:"offset" validate:"min=0"`
+	Content io.Reader `json:"-" validate:"required"`
+}
+
+// FinalizeResumableUpload represents the payload completing a resumable upload.
+// @Description Payload for verifying a fully received archive and queueing it for extraction
+// @Name FinalizeResumableUpload
+type FinalizeResumableUpload struct {
+	ID string `json:"id" validate:"required,uuid4"`
+}
+
+// CreateProxyDeployment represents the payload for registering a deployment served by upstreams.
+// @Description Proxy deployment DTO; one upstream must cover the "/" path
+// @Name CreateProxyDeployment
+type CreateProxyDeployment struct {
+	ProjectID string             `json:"project_id" validate:"required,uuid4"`
+	Hash      string             `json:"hash" validate:"required,max=255"`
+	Upstreams []gateway.Upstream `json:"upstreams" validate:"required,min=1,max=20,dive"`
+}
+
 // UpdateDeploymentStatus represents the payload for updating deployment status.
 // @Description Deployment status update DTO
 // @Name UpdateDeploymentStatus
 type UpdateDeploymentStatus struct {
-	ID     string `json:"id" validate:"required"`
-	Status string `json:"status" validate:"required,oneof=pending ready error expired"`
+	ID      string `json:"id" validate:"required"`
+	Status  string `json:"status" validate:"required,oneof=pending ready error expired"`
+	Message string `json:"message,omitempty"` // Stored as the error message, cleared when empty
+}
+
+// ConfigureDeployment represents the settings resolved for a deployment while it is processed.
+// @Description Deployment settings resolved by the deployment worker
+// @Name ConfigureDeployment
+type ConfigureDeployment struct {
+	ID          string                 `json:"id" validate:"required"`
+	EntryPath   string                 `json:"entry_path" validate:"required"`
+	ServingMode string                 `json:"serving_mode" validate:"required,oneof=spa static clean_urls"`
+	Redirects   []gateway.RedirectRule `json:"redirects" validate:"dive"`
+	Headers     []gateway.HeaderRule   `json:"headers" validate:"dive"`
+	TTL         *int                   `json:"ttl,omitempty" validate:"omitempty,min=1"` // Days after creation; keeps the current expiry when nil
+	Metadata    map[string]string      `json:"metadata"`
+	Upstreams   []gateway.Upstream     `json:"upstreams" validate:"dive"`
 }
 
 type DeploymentRepository interface {
 	GetByID(ctx context.Context, d GetSingleDeployment) (*Deployment, error)
+	GetByRoute(ctx context.Context, d GetRoutedDeployment) (*Deployment, error)
 	GetPaged(ctx context.Context, params GetPagedDeployment) (*DeploymentPaged, error)
 	Upload(ctx context.Context, d UploadDeployment) (string, error)
+	CreateProxy(ctx context.Context, d CreateProxyDeployment) (string, error)
 	UpdateStatus(ctx context.Context, d UpdateDeploymentStatus) error
+	Configure(ctx context.Context, d ConfigureDeployment) error
 	GetExpired(ctx context.Context) ([]Deployment, error)
+	GetArchiveFormats(ctx context.Context, projectID string) ([]string, error)
+	SaveFiles(ctx context.Context, deploymentID string, files []DeploymentFile) error
+	GetFiles(ctx context.Context, params GetDeploymentFiles) (*DeploymentFilePaged, error)
+	ListFiles(ctx context.Context, deploymentID string) ([]DeploymentFile, error)
+	CreateUpload(ctx context.Context, deploymentID string, d CreateResumableUpload) error
+	GetUpload(ctx context.Context, d GetResumableUpload) (*ResumableUpload, error)
+	TouchUpload(ctx context.Context, deploymentID string) error
+	DeleteUpload(ctx context.Context, deploymentID string) (bool, error)
+	GetAbandonedUploads(ctx context.Context) ([]string, error)
+	GetCredentials(ctx context.Context, deploymentID string) ([]gateway.Credential, error)
+	SetProtection(ctx context.Context, d SetDeploymentProtection, credentials []gateway.Credential) error
+	RemoveProtection(ctx context.Context, d RemoveDeploymentProtection) error
 }
 
 type DeploymentService interface {
 	GetDeploymentByID(ctx context.Context, d GetSingleDeployment) (*Deployment, error)
 	GetPagedDeployments(ctx context.Context, params GetPagedDeployment) (*DeploymentPaged, error)
 	Upload(ctx context.Context, d UploadDeployment) (*Deployment, error)
+	CreateProxy(ctx context.Context, d CreateProxyDeployment) (*Deployment, error)
+	CreateDeltaUpload(ctx context.Context, d CreateDeltaUpload) (*DeltaUpload, error)
+	UploadDeltaBlob(ctx context.Context, d UploadDeltaBlob) (*DeltaUpload, error)
+	FinalizeDeltaUpload(ctx context.Context, d FinalizeDeltaUpload) (*Deployment, error)
+	CreateResumableUpload(ctx context.Context, d CreateResumableUpload) (*ResumableUpload, error)
+	GetResumableUpload(ctx context.Context, d GetResumableUpload) (*ResumableUpload, error)
+	UploadChunk(ctx context.Context, d UploadChunk) (*ResumableUpload, error)
+	FinalizeResumableUpload(ctx context.Context, d FinalizeResumableUpload) (*Deployment, error)
+	GetDeploymentFiles(ctx context.Context, params GetDeploymentFiles) (*DeploymentFilePaged, error)
+	GetDeploymentDiff(ctx context.Context, params GetDeploymentDiff) (*DeploymentDiff, error)
 	UpdateDeploymentStatus(ctx context.Context, d UpdateDeploymentStatus) (*Deployment, error)
+	SetDeploymentProtection(ctx context.Context, d SetDeploymentProtection) (*Deployment, error)
+	RemoveDeploymentProtection(ctx context.Context, d RemoveDeploymentProtection) (*Deployment, error)
 }
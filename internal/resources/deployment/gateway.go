@@ -0,0 +1,175 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
+	"github.com/jackc/pgx/v5"
+)
+
+// GatewayResolver resolves hostnames served by the static gateway to projects and ready deployments.
+type GatewayResolver struct {
+	repo        DeploymentRepository
+	projectRepo project.ProjectRepository
+}
+
+// NewGatewayResolver creates a resolver backed by the deployment and project repositories.
+func NewGatewayResolver(repo DeploymentRepository, projectRepo project.ProjectRepository) *GatewayResolver {
+	return &GatewayResolver{repo: repo, projectRepo: projectRepo}
+}
+
+// ResolveDeployment returns the ready deployment for a project name and preview ID, along with the
+// maintenance mode and preview access rules of its project. The deployment's own credentials are
+// required when it has any, otherwise the project's unless only its production hostname is protected.
+// It returns nil when no ready deployment matches.
+func (r *GatewayResolver) ResolveDeployment(ctx context.Context, projectName, previewID string) (*gateway.GatewayDeployment, error) {
+	return r.resolve(ctx, GetRoutedDeployment{ProjectName: projectName, PreviewID: previewID})
+}
+
+// ResolvePinned returns the ready deployment a client pinned on the production hostname, named by its
+// preview ID or hash, guarded as on its preview hostname. It returns nil when no ready deployment matches.
+func (r *GatewayResolver) ResolvePinned(ctx context.Context, projectName, key string) (*gateway.GatewayDeployment, error) {
+	return r.resolve(ctx, GetRoutedDeployment{ProjectName: projectName, PreviewID: key, Hash: key})
+}
+
+// ResolveProduction returns the project served on a production hostname with its production and canary
+// deployments, leaving out those no longer ready. It returns nil when no live project has the name.
+func (r *GatewayResolver) ResolveProduction(ctx context.Context, projectName string) (*gateway.GatewayProject, []gateway.GatewayDeployment, error) {
+	projectID, err := r.projectRepo.GetIDByName(ctx, projectName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := r.projectRepo.GetByID(ctx, project.GetSingleProject{ID: projectID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	credentials, err := r.projectRepo.GetCredentials(ctx, project.GetSingleProject{ID: projectID})
+	if err != nil {
+		return nil, nil, err
+	}
+	gp := ToGatewayProject(*p, credentials)
+
+	var deps []gateway.GatewayDeployment
+	for _, id := range []string{gp.ProductionDeploymentID, canaryDeploymentID(gp)} {
+		if id == "" {
+			continue
+		}
+		d, err := r.repo.GetByID(ctx, GetSingleDeployment{ID: id})
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if d.Status == StatusReady {
+			deps = append(deps, ToGatewayDeployment(*d))
+		}
+	}
+	return &gp, deps, nil
+}
+
+// resolve looks up a routed deployment and the rules guarding its preview hostname.
+func (r *GatewayResolver) resolve(ctx context.Context, route GetRoutedDeployment) (*gateway.GatewayDeployment, error) {
+	d, err := r.repo.GetByRoute(ctx, route)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := r.projectRepo.GetByID(ctx, project.GetSingleProject{ID: d.ProjectID})
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := r.repo.GetCredentials(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 && p.Protection != nil && p.Protection.Scope != gateway.AuthScopeProduction {
+		credentials, err = r.projectRepo.GetCredentials(ctx, project.GetSingleProject{ID: d.ProjectID})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dep := ToGatewayDeployment(*d)
+	dep.Credentials = credentials
+	dep.Access = p.PreviewAccess
+	if p.Maintenance != nil {
+		dep.Maintenance = &gateway.Maintenance{Page: p.Maintenance.Page, BypassIPs: p.Maintenance.BypassIPs}
+	}
+	return &dep, nil
+}
+
+// canaryDeploymentID returns the deployment a project's canary sends traffic to, if any.
+func canaryDeploymentID(p gateway.GatewayProject) string {
+	if p.Canary == nil {
+		return ""
+	}
+	return p.Canary.DeploymentID
+}
+
+// ToGatewayProject converts a project and its basic auth users into the shape used for gateway configuration.
+func ToGatewayProject(p project.Project, credentials []gateway.Credential) gateway.GatewayProject {
+	gp := gateway.GatewayProject{
+		ID:                p.ID,
+		Name:              p.Name,
+		Redirects:         p.Redirects,
+		Headers:           p.Headers,
+		Credentials:       credentials,
+		ProductionAccess:  p.ProductionAccess,
+		PreviewAccess:     p.PreviewAccess,
+		RateLimit:         p.RateLimit,
+		DeploymentRouting: p.DeploymentRouting,
+	}
+	if p.ProductionDeploymentID != nil {
+		gp.ProductionDeploymentID = *p.ProductionDeploymentID
+	}
+	if p.Protection != nil {
+		gp.AuthScope = p.Protection.Scope
+	}
+	if p.Canary != nil {
+		gp.Canary = &gateway.Canary{DeploymentID: p.Canary.DeploymentID, Weight: p.Canary.Weight, Key: p.Canary.Key}
+	}
+	if p.Maintenance != nil {
+		gp.Maintenance = &gateway.Maintenance{Page: p.Maintenance.Page, BypassIPs: p.Maintenance.BypassIPs}
+	}
+	return gp
+}
+
+// ToGatewayDeployment converts a deployment into the shape used for gateway configuration.
+func ToGatewayDeployment(d Deployment) gateway.GatewayDeployment {
+	projectName := ""
+	if d.ProjectName != nil {
+		projectName = *d.ProjectName
+	}
+	entryPath := d.EntryPath
+	servingMode := ""
+	if d.ServingMode != nil {
+		servingMode = *d.ServingMode
+	}
+
+	return gateway.GatewayDeployment{
+		ID:          d.ID,
+		Hash:        d.Hash,
+		PreviewID:   d.PreviewID,
+		ProjectID:   d.ProjectID,
+		ProjectName: projectName,
+		EntryPath:   &entryPath,
+		ServingMode: servingMode,
+		Redirects:   d.Redirects,
+		Headers:     d.Headers,
+		Upstreams:   d.Upstreams,
+	}
+}
@@ -0,0 +1,76 @@
+package deployment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/pkgs/response"
+	"github.com/dimasbaguspm/infario/pkgs/validator"
+)
+
+// ManifestFile is the optional deployment manifest looked up at the archive root.
+const ManifestFile = "infario.json"
+
+// Manifest declares deployment settings inside the uploaded archive.
+// Settings are merged in this order, first match wins: upload form fields, the manifest,
+// project settings, then defaults. Manifest rules are appended after _redirects and _headers rules.
+// @Description Deployment manifest read from infario.json at the archive root
+// @Name Manifest
+type Manifest struct {
+	EntryPath   string                 `json:"entry_path,omitempty" validate:"omitempty,startswith=/"`
+	ServingMode string                 `json:"serving_mode,omitempty" validate:"omitempty,oneof=spa static clean_urls"`
+	TTL         *int                   `json:"ttl,omitempty" validate:"omitempty,min=1,max=365"` // Days until the deployment expires
+	Redirects   []gateway.RedirectRule `json:"redirects,omitempty" validate:"omitempty,dive"`
+	Headers     []gateway.HeaderRule   `json:"headers,omitempty" validate:"omitempty,dive"`
+	Metadata    map[string]string      `json:"metadata,omitempty" validate:"omitempty,max=50,dive,keys,min=1,max=64,endkeys,max=1024"`
+	Upstreams   []gateway.Upstream     `json:"upstreams,omitempty" validate:"omitempty,max=20,dive"` // Backends proxied next to the archive's files, e.g. under /api/
+}
+
+// ManifestError reports every invalid field of a manifest, keyed by field path.
+type ManifestError struct {
+	Fields map[string]string
+}
+
+func (e *ManifestError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + ": " + e.Fields[field]
+	}
+	return ManifestFile + ": " + strings.Join(parts, "; ")
+}
+
+// ParseManifest decodes and validates a manifest. Unknown fields are rejected so typos do not
+// silently fall back to defaults.
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&m); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &ManifestError{Fields: map[string]string{typeErr.Field: fmt.Sprintf("Must be a %s", typeErr.Type)}}
+		}
+		return nil, fmt.Errorf("%s: invalid JSON: %w", ManifestFile, err)
+	}
+
+	if err := validator.Validate.Struct(m); err != nil {
+		if fields := response.MapValidationErrorPaths(err); len(fields) > 0 {
+			return nil, &ManifestError{Fields: fields}
+		}
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+
+	return &m, nil
+}
@@ -3,13 +3,14 @@ package deployment
 import (
 	"net/http"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
 	"github.com/dimasbaguspm/infario/internal/platform/engine"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
 
-func InitHttp(mux *http.ServeMux, pgx *pgxpool.Pool, redisClient *redis.Client, fileEngine *engine.FileEngine) {
+func InitHttp(mux *http.ServeMux, pgx *pgxpool.Pool, redisClient *redis.Client, fileEngine *engine.FileEngine, routing gateway.Routing) {
 	repo := NewPostgresRepository(pgx)
-	service := NewService(repo, redisClient, fileEngine)
+	service := NewService(repo, redisClient, fileEngine, routing)
 	RegisterRoutes(mux, *service)
 }
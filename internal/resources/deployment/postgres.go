@@ -2,8 +2,13 @@ package deployment
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,26 +23,119 @@ func NewPostgresRepository(db *pgxpool.Pool) *PostgresRepository {
 func (r *PostgresRepository) GetByID(ctx context.Context, d GetSingleDeployment) (*Deployment, error) {
 	query := `
 		SELECT
-			id,
-			project_id,
-			hash,
-			status,
-			created_at,
-			expired_at,
-			entry_path
-		FROM deployments
-		WHERE id = $1
+			d.id,
+			d.project_id,
+			d.hash,
+			d.preview_id,
+			d.status,
+			d.created_at,
+			d.expired_at,
+			d.entry_path,
+			d.serving_mode,
+			d.redirects,
+			d.headers,
+			d.metadata,
+			d.type,
+			d.upstreams,
+			d.total_size,
+			d.file_count,
+			d.error_message,
+			p.name AS project_name,
+			COALESCE(p.production_deployment_id = d.id, false) AS production,
+			(SELECT COALESCE(array_agg(c.username ORDER BY c.username), '{}') FROM deployment_credentials c WHERE c.deployment_id = d.id) AS usernames
+		FROM deployments d
+		LEFT JOIN projects p ON p.id = d.project_id
+		WHERE d.id = $1
 	`
 
 	deployment := &Deployment{}
+	var usernames []string
 	err := r.db.QueryRow(ctx, query, d.ID).Scan(
 		&deployment.ID,
 		&deployment.ProjectID,
 		&deployment.Hash,
+		&deployment.PreviewID,
 		&deployment.Status,
 		&deployment.CreatedAt,
 		&deployment.ExpiredAt,
 		&deployment.EntryPath,
+		&deployment.ServingMode,
+		&deployment.Redirects,
+		&deployment.Headers,
+		&deployment.Metadata,
+		&deployment.Type,
+		&deployment.Upstreams,
+		&deployment.TotalSize,
+		&deployment.FileCount,
+		&deployment.ErrorMessage,
+		&deployment.ProjectName,
+		&deployment.Production,
+		&usernames,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deployment.Protection = newProtection(usernames)
+	return deployment, nil
+}
+
+// GetByRoute retrieves the ready deployment matching a project name and preview ID, case-insensitively.
+// With a hash set, deployments with that hash match too; a preview ID match wins, then the newest.
+func (r *PostgresRepository) GetByRoute(ctx context.Context, d GetRoutedDeployment) (*Deployment, error) {
+	query := `
+		SELECT
+			d.id,
+			d.project_id,
+			d.hash,
+			d.preview_id,
+			d.status,
+			d.created_at,
+			d.expired_at,
+			d.entry_path,
+			d.serving_mode,
+			d.redirects,
+			d.headers,
+			d.metadata,
+			d.type,
+			d.upstreams,
+			d.total_size,
+			d.file_count,
+			d.error_message,
+			p.name AS project_name,
+			COALESCE(p.production_deployment_id = d.id, false) AS production
+		FROM deployments d
+		JOIN projects p ON p.id = d.project_id
+		WHERE LOWER(p.name) = LOWER($1)
+			AND (d.preview_id = LOWER($2) OR ($4 <> '' AND LOWER(d.hash) = LOWER($4)))
+			AND d.status = $3
+			AND p.deleted_at IS NULL
+		ORDER BY d.preview_id = LOWER($2) DESC, d.created_at DESC
+		LIMIT 1
+	`
+
+	deployment := &Deployment{}
+	err := r.db.QueryRow(ctx, query, d.ProjectName, d.PreviewID, StatusReady, d.Hash).Scan(
+		&deployment.ID,
+		&deployment.ProjectID,
+		&deployment.Hash,
+		&deployment.PreviewID,
+		&deployment.Status,
+		&deployment.CreatedAt,
+		&deployment.ExpiredAt,
+		&deployment.EntryPath,
+		&deployment.ServingMode,
+		&deployment.Redirects,
+		&deployment.Headers,
+		&deployment.Metadata,
+		&deployment.Type,
+		&deployment.Upstreams,
+		&deployment.TotalSize,
+		&deployment.FileCount,
+		&deployment.ErrorMessage,
+		&deployment.ProjectName,
+		&deployment.Production,
 	)
 
 	if err != nil {
@@ -57,11 +155,23 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedDeploy
 				d.id,
 				d.project_id,
 				d.hash,
+				d.preview_id,
 				d.status,
 				d.created_at,
 				d.expired_at,
 				d.entry_path,
+				d.serving_mode,
+				d.redirects,
+				d.headers,
+				d.metadata,
+				d.type,
+				d.upstreams,
+				d.total_size,
+				d.file_count,
+				d.error_message,
 				p.name AS project_name,
+				COALESCE(p.production_deployment_id = d.id, false) AS production,
+				(SELECT COALESCE(array_agg(c.username ORDER BY c.username), '{}') FROM deployment_credentials c WHERE c.deployment_id = d.id) AS usernames,
 				COUNT(*) OVER () AS total_count
 			FROM deployments d
 			LEFT JOIN projects p ON p.id = d.project_id
@@ -74,11 +184,23 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedDeploy
 			id,
 			project_id,
 			hash,
+			preview_id,
 			status,
 			created_at,
 			expired_at,
 			entry_path,
+			serving_mode,
+			redirects,
+			headers,
+			metadata,
+			type,
+			upstreams,
+			total_size,
+			file_count,
+			error_message,
 			project_name,
+			production,
+			usernames,
 			total_count
 		FROM deployments_cte
 	`
@@ -95,21 +217,35 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedDeploy
 	for rows.Next() {
 		deployment := &Deployment{}
 		var projectName *string
+		var usernames []string
 		err := rows.Scan(
 			&deployment.ID,
 			&deployment.ProjectID,
 			&deployment.Hash,
+			&deployment.PreviewID,
 			&deployment.Status,
 			&deployment.CreatedAt,
 			&deployment.ExpiredAt,
 			&deployment.EntryPath,
+			&deployment.ServingMode,
+			&deployment.Redirects,
+			&deployment.Headers,
+			&deployment.Metadata,
+			&deployment.Type,
+			&deployment.Upstreams,
+			&deployment.TotalSize,
+			&deployment.FileCount,
+			&deployment.ErrorMessage,
 			&projectName,
+			&deployment.Production,
+			&usernames,
 			&totalCount,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan deployment row: %w", err)
 		}
 		deployment.ProjectName = projectName
+		deployment.Protection = newProtection(usernames)
 		deployments = append(deployments, *deployment)
 	}
 
@@ -130,39 +266,71 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedDeploy
 }
 
 func (r *PostgresRepository) Upload(ctx context.Context, d UploadDeployment) (string, error) {
-	var ID *string
-
 	// Default TTL: 30 days from now
 	expiredAt := "NOW() + INTERVAL '30 days'"
 
 	query := fmt.Sprintf(`
-		INSERT INTO deployments (project_id, hash, status, entry_path, expired_at)
-		VALUES ($1, $2, $3, $4, %s)
+		INSERT INTO deployments (project_id, hash, status, entry_path, serving_mode, expired_at, preview_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), %s, $6)
 		RETURNING id
 	`, expiredAt)
 
-	err := r.db.QueryRow(ctx, query,
+	ID, err := r.insertWithPreviewID(ctx, query, d.Hash,
 		d.ProjectID,
 		d.Hash,
 		StatusPending,
 		d.EntryPath,
-	).Scan(&ID)
-
+		d.ServingMode,
+	)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload deployment: %w", err)
 	}
 
-	return *ID, nil
+	return ID, nil
+}
+
+// CreateProxy inserts a pending proxy deployment with the same 30-day TTL as uploads.
+func (r *PostgresRepository) CreateProxy(ctx context.Context, d CreateProxyDeployment) (string, error) {
+	query := `
+		INSERT INTO deployments (project_id, hash, status, entry_path, type, upstreams, expired_at, preview_id)
+		VALUES ($1, $2, $3, '/', $4, $5, NOW() + INTERVAL '30 days', $6)
+		RETURNING id
+	`
+
+	ID, err := r.insertWithPreviewID(ctx, query, d.Hash, d.ProjectID, d.Hash, StatusPending, TypeProxy, d.Upstreams)
+	if err != nil {
+		return "", fmt.Errorf("failed to create proxy deployment: %w", err)
+	}
+
+	return ID, nil
+}
+
+// insertWithPreviewID runs an INSERT returning the deployment ID, passing a preview ID derived from
+// hash as its last argument. Candidates already taken within the project are skipped.
+func (r *PostgresRepository) insertWithPreviewID(ctx context.Context, query, hash string, args ...any) (string, error) {
+	var err error
+	for _, previewID := range previewIDCandidates(hash) {
+		var ID string
+		err = r.db.QueryRow(ctx, query, append(args, previewID)...).Scan(&ID)
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == previewIDConstraint {
+			continue
+		}
+		return ID, err
+	}
+	return "", err
 }
 
 func (r *PostgresRepository) UpdateStatus(ctx context.Context, d UpdateDeploymentStatus) error {
 	query := `
 		UPDATE deployments
-		SET status = $1
-		WHERE id = $2
+		SET status = $1,
+			error_message = NULLIF($2, '')
+		WHERE id = $3
 	`
 
-	_, err := r.db.Exec(ctx, query, d.Status, d.ID)
+	_, err := r.db.Exec(ctx, query, d.Status, d.Message, d.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update deployment status: %w", err)
 	}
@@ -170,21 +338,87 @@ func (r *PostgresRepository) UpdateStatus(ctx context.Context, d UpdateDeploymen
 	return nil
 }
 
-// GetExpired retrieves all deployments that have exceeded their TTL.
+// Configure stores the settings resolved while processing a deployment.
+// A TTL recomputes the expiry from the creation time; without one the upload default is kept.
+func (r *PostgresRepository) Configure(ctx context.Context, d ConfigureDeployment) error {
+	query := `
+		UPDATE deployments
+		SET entry_path = $1,
+			serving_mode = $2,
+			redirects = $3,
+			headers = $4,
+			metadata = $5,
+			upstreams = $6,
+			expired_at = COALESCE(created_at + make_interval(days => $7), expired_at)
+		WHERE id = $8
+	`
+
+	redirects := d.Redirects
+	if redirects == nil {
+		redirects = []gateway.RedirectRule{}
+	}
+	headers := d.Headers
+	if headers == nil {
+		headers = []gateway.HeaderRule{}
+	}
+	metadata := d.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	upstreams := d.Upstreams
+	if upstreams == nil {
+		upstreams = []gateway.Upstream{}
+	}
+
+	_, err := r.db.Exec(ctx, query, d.EntryPath, d.ServingMode, redirects, headers, metadata, upstreams, d.TTL, d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to configure deployment: %w", err)
+	}
+
+	return nil
+}
+
+// GetArchiveFormats returns the upload formats a project accepts, empty when every format is accepted.
+func (r *PostgresRepository) GetArchiveFormats(ctx context.Context, projectID string) ([]string, error) {
+	var formats []string
+	err := r.db.QueryRow(ctx, `SELECT archive_formats FROM projects WHERE id = $1`, projectID).Scan(&formats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive formats: %w", err)
+	}
+	return formats, nil
+}
+
+// GetExpired retrieves all deployments that have exceeded their TTL. Deployments a project serves
+// as production or canary never expire while they are promoted.
 func (r *PostgresRepository) GetExpired(ctx context.Context) ([]Deployment, error) {
 	query := `
 		SELECT
 			id,
 			project_id,
 			hash,
+			preview_id,
 			status,
 			created_at,
 			expired_at,
-			entry_path
-		FROM deployments
+			entry_path,
+			serving_mode,
+			redirects,
+			headers,
+			metadata,
+			type,
+			upstreams,
+			total_size,
+			file_count,
+			error_message
+		FROM deployments d
 		WHERE expired_at IS NOT NULL
 		AND expired_at <= NOW()
 		AND status != $1
+		AND NOT EXISTS (
+			SELECT 1 FROM projects p
+			WHERE p.production_deployment_id = d.id OR p.canary_deployment_id = d.id
+		)
 		ORDER BY expired_at ASC
 	`
 
@@ -201,10 +435,20 @@ func (r *PostgresRepository) GetExpired(ctx context.Context) ([]Deployment, erro
 			&deployment.ID,
 			&deployment.ProjectID,
 			&deployment.Hash,
+			&deployment.PreviewID,
 			&deployment.Status,
 			&deployment.CreatedAt,
 			&deployment.ExpiredAt,
 			&deployment.EntryPath,
+			&deployment.ServingMode,
+			&deployment.Redirects,
+			&deployment.Headers,
+			&deployment.Metadata,
+			&deployment.Type,
+			&deployment.Upstreams,
+			&deployment.TotalSize,
+			&deployment.FileCount,
+			&deployment.ErrorMessage,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan expired deployment: %w", err)
 		}
@@ -217,3 +461,294 @@ func (r *PostgresRepository) GetExpired(ctx context.Context) ([]Deployment, erro
 
 	return deployments, nil
 }
+
+// SaveFiles replaces the file manifest of a deployment and records its total size and file count.
+func (r *PostgresRepository) SaveFiles(ctx context.Context, deploymentID string, files []DeploymentFile) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM deployment_files WHERE deployment_id = $1`, deploymentID); err != nil {
+			return fmt.Errorf("failed to clear deployment files: %w", err)
+		}
+
+		var totalSize int64
+		rows := make([][]any, len(files))
+		for i, f := range files {
+			mode, err := strconv.ParseUint(f.Mode, 8, 32)
+			if err != nil {
+				return fmt.Errorf("invalid mode %q of %s: %w", f.Mode, f.Path, err)
+			}
+			rows[i] = []any{deploymentID, f.Path, f.Size, int32(mode), f.SHA256, f.ContentType}
+			totalSize += f.Size
+		}
+
+		_, err := tx.CopyFrom(ctx,
+			pgx.Identifier{"deployment_files"},
+			[]string{"deployment_id", "path", "size", "mode", "sha256", "content_type"},
+			pgx.CopyFromRows(rows),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert deployment files: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE deployments
+			SET total_size = $1,
+				file_count = $2
+			WHERE id = $3
+		`, totalSize, len(files), deploymentID)
+		if err != nil {
+			return fmt.Errorf("failed to update deployment totals: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetFiles lists the files of a deployment ordered by path, optionally under a path prefix.
+func (r *PostgresRepository) GetFiles(ctx context.Context, params GetDeploymentFiles) (*DeploymentFilePaged, error) {
+	query := `
+		SELECT
+			path,
+			size,
+			mode,
+			sha256,
+			content_type,
+			COUNT(*) OVER () AS total_count
+		FROM deployment_files
+		WHERE deployment_id = $1
+		AND ($2 = '' OR starts_with(path, $2))
+		ORDER BY path
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(ctx, query, params.ID, params.Prefix, params.PageSize, params.Offset())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployment files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make([]DeploymentFile, 0)
+	var totalCount int64
+
+	for rows.Next() {
+		var f DeploymentFile
+		var mode int32
+		if err := rows.Scan(&f.Path, &f.Size, &mode, &f.SHA256, &f.ContentType, &totalCount); err != nil {
+			return nil, fmt.Errorf("failed to scan deployment file row: %w", err)
+		}
+		f.Mode = fmt.Sprintf("%04o", mode)
+		files = append(files, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deployment file rows: %w", err)
+	}
+
+	// Calculate total pages
+	pageCount := (totalCount + int64(params.PageSize) - 1) / int64(params.PageSize)
+
+	return &DeploymentFilePaged{
+		Items:      files,
+		TotalCount: totalCount,
+		PageSize:   params.PageSize,
+		PageNumber: params.PageNumber,
+		PageCount:  pageCount,
+	}, nil
+}
+
+// ListFiles returns every file of a deployment ordered by path.
+func (r *PostgresRepository) ListFiles(ctx context.Context, deploymentID string) ([]DeploymentFile, error) {
+	query := `
+		SELECT path, size, mode, sha256, content_type
+		FROM deployment_files
+		WHERE deployment_id = $1
+		ORDER BY path
+	`
+
+	rows, err := r.db.Query(ctx, query, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployment files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make([]DeploymentFile, 0)
+	for rows.Next() {
+		var f DeploymentFile
+		var mode int32
+		if err := rows.Scan(&f.Path, &f.Size, &mode, &f.SHA256, &f.ContentType); err != nil {
+			return nil, fmt.Errorf("failed to scan deployment file row: %w", err)
+		}
+		f.Mode = fmt.Sprintf("%04o", mode)
+		files = append(files, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deployment file rows: %w", err)
+	}
+
+	return files, nil
+}
+
+// CreateUpload records the resumable upload of a deployment's archive.
+func (r *PostgresRepository) CreateUpload(ctx context.Context, deploymentID string, d CreateResumableUpload) error {
+	query := `
+		INSERT INTO deployment_uploads (deployment_id, filename, size, sha256, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+	`
+
+	_, err := r.db.Exec(ctx, query, deploymentID, d.Filename, d.Size, d.SHA256, int64(UploadSessionTTL.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	return nil
+}
+
+// GetUpload retrieves a resumable upload that has not expired. The offset is tracked by the file
+// engine and left unset.
+func (r *PostgresRepository) GetUpload(ctx context.Context, d GetResumableUpload) (*ResumableUpload, error) {
+	query := `
+		SELECT filename, size, sha256, expires_at
+		FROM deployment_uploads
+		WHERE deployment_id = $1
+		AND expires_at > NOW()
+	`
+
+	upload := &ResumableUpload{}
+	err := r.db.QueryRow(ctx, query, d.ID).Scan(
+		&upload.Filename,
+		&upload.Size,
+		&upload.SHA256,
+		&upload.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// TouchUpload pushes back the expiry of a resumable upload that received a chunk.
+func (r *PostgresRepository) TouchUpload(ctx context.Context, deploymentID string) error {
+	query := `
+		UPDATE deployment_uploads
+		SET expires_at = NOW() + $1 * INTERVAL '1 second'
+		WHERE deployment_id = $2
+	`
+
+	_, err := r.db.Exec(ctx, query, int64(UploadSessionTTL.Seconds()), deploymentID)
+	if err != nil {
+		return fmt.Errorf("failed to touch upload: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUpload removes the record of a resumable upload, reporting whether it existed. Finalizing
+// and abandoning an upload both delete it first, so only one of them proceeds.
+func (r *PostgresRepository) DeleteUpload(ctx context.Context, deploymentID string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM deployment_uploads WHERE deployment_id = $1`, deploymentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete upload: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetAbandonedUploads returns the deployment IDs of resumable uploads past their expiry.
+func (r *PostgresRepository) GetAbandonedUploads(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT deployment_id
+		FROM deployment_uploads
+		WHERE expires_at <= NOW()
+		ORDER BY expires_at ASC
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get abandoned uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan abandoned upload: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating abandoned upload rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetCredentials returns the basic auth users of a deployment, ordered by username.
+func (r *PostgresRepository) GetCredentials(ctx context.Context, deploymentID string) ([]gateway.Credential, error) {
+	query := `
+		SELECT username, password_hash
+		FROM deployment_credentials
+		WHERE deployment_id = $1
+		ORDER BY username
+	`
+
+	rows, err := r.db.Query(ctx, query, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []gateway.Credential
+	for rows.Next() {
+		var c gateway.Credential
+		if err := rows.Scan(&c.Username, &c.PasswordHash); err != nil {
+			return nil, fmt.Errorf("failed to scan credential row: %w", err)
+		}
+		credentials = append(credentials, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating credential rows: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// SetProtection replaces the basic auth users of a deployment in a single transaction.
+func (r *PostgresRepository) SetProtection(ctx context.Context, d SetDeploymentProtection, credentials []gateway.Credential) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM deployment_credentials WHERE deployment_id = $1`, d.ID); err != nil {
+			return fmt.Errorf("failed to clear credentials: %w", err)
+		}
+
+		for _, c := range credentials {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO deployment_credentials (deployment_id, username, password_hash)
+				VALUES ($1, $2, $3)
+			`, d.ID, c.Username, c.PasswordHash)
+			if err != nil {
+				return fmt.Errorf("failed to insert credential: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveProtection deletes the basic auth users of a deployment, leaving its preview hostname to the project's protection.
+func (r *PostgresRepository) RemoveProtection(ctx context.Context, d RemoveDeploymentProtection) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM deployment_credentials WHERE deployment_id = $1`, d.ID); err != nil {
+		return fmt.Errorf("failed to clear credentials: %w", err)
+	}
+	return nil
+}
+
+// newProtection builds the protection settings of a deployment, or nil when it has no users of its own.
+func newProtection(usernames []string) *DeploymentProtection {
+	if len(usernames) == 0 {
+		return nil
+	}
+	return &DeploymentProtection{Usernames: usernames}
+}
@@ -0,0 +1,55 @@
+package deployment
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	// previewIDConstraint is the unique index keeping preview IDs distinct within a project;
+	// inserts violating it (SQLSTATE 23505) retry with the next candidate.
+	previewIDConstraint = "idx_deployments_project_preview_id"
+
+	previewIDAlphabet  = "abcdefghijklmnopqrstuvwxyz0123456789"
+	randomPreviewIDLen = 10
+	randomPreviewIDs   = 5
+)
+
+// previewIDLengths are the hash prefix lengths tried, shortest first, like abbreviated git SHAs.
+var previewIDLengths = []int{8, 12, 16}
+
+// previewIDCandidates lists the preview IDs to try for a hash, in order. Every candidate is a lowercase
+// DNS label: first prefixes of the hash's letters and digits, then random IDs for hashes that have
+// none or whose prefixes are all taken within the project.
+func previewIDCandidates(hash string) []string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(hash) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		}
+	}
+	label := b.String()
+
+	var candidates []string
+	for _, n := range previewIDLengths {
+		candidate := label[:min(n, len(label))]
+		if candidate != "" && (len(candidates) == 0 || candidates[len(candidates)-1] != candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	for range randomPreviewIDs {
+		candidates = append(candidates, randomPreviewID())
+	}
+	return candidates
+}
+
+// randomPreviewID returns a random lowercase alphanumeric ID.
+func randomPreviewID() string {
+	buf := make([]byte, randomPreviewIDLen)
+	rand.Read(buf)
+	for i, v := range buf {
+		buf[i] = previewIDAlphabet[int(v)%len(previewIDAlphabet)]
+	}
+	return string(buf)
+}
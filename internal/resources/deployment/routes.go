@@ -1,10 +1,15 @@
 package deployment
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/dimasbaguspm/infario/internal/platform/engine"
 	"github.com/dimasbaguspm/infario/pkgs/request"
 	"github.com/dimasbaguspm/infario/pkgs/response"
+	"github.com/jackc/pgx/v5"
 )
 
 type handler struct {
@@ -16,7 +21,19 @@ func RegisterRoutes(mux *http.ServeMux, s Service) {
 
 	mux.HandleFunc("GET /deployments", h.handleGetPagedDeployments)
 	mux.HandleFunc("GET /deployments/{id}", h.handleGetDeployment)
+	mux.HandleFunc("GET /deployments/{id}/files", h.handleGetDeploymentFiles)
+	mux.HandleFunc("GET /deployments/{id}/diff", h.handleGetDeploymentDiff)
 	mux.HandleFunc("POST /deployments/upload", h.handleUpload)
+	mux.HandleFunc("POST /deployments/proxy", h.handleCreateProxy)
+	mux.HandleFunc("POST /deployments/delta", h.handleCreateDeltaUpload)
+	mux.HandleFunc("PUT /deployments/{id}/blobs/{sha256}", h.handleUploadDeltaBlob)
+	mux.HandleFunc("POST /deployments/{id}/finalize", h.handleFinalizeDeltaUpload)
+	mux.HandleFunc("POST /deployments/resumable", h.handleCreateResumableUpload)
+	mux.HandleFunc("GET /deployments/{id}/upload", h.handleGetResumableUpload)
+	mux.HandleFunc("PATCH /deployments/{id}/upload", h.handleUploadChunk)
+	mux.HandleFunc("POST /deployments/{id}/upload/finalize", h.handleFinalizeResumableUpload)
+	mux.HandleFunc("PUT /deployments/{id}/protection", h.handleSetProtection)
+	mux.HandleFunc("DELETE /deployments/{id}/protection", h.handleRemoveProtection)
 }
 
 // handleGetDeployment retrieves a deployment by its ID.
@@ -73,15 +90,96 @@ func (h *handler) handleGetPagedDeployments(w http.ResponseWriter, r *http.Reque
 	response.JSON(w, http.StatusOK, page)
 }
 
-// handleUpload uploads a deployment artifact (zip or tar.gz) with default 30-day TTL.
+// handleGetDeploymentFiles lists the files of a deployment with pagination.
+// @Summary      List the files of a deployment
+// @Tags         deployments
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Param prefix query string false "Only files whose path starts with this prefix, e.g. /assets/"
+// @Param pageNumber query int false "Page number (default: 1)" default(1)
+// @Param pageSize query int false "Page size (default: 25, max: 100)" default(25)
+// @Success      200 {object} DeploymentFilePaged
+// @Failure      404 {object} response.ErrorResponse "Deployment not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/files [get]
+func (h *handler) handleGetDeploymentFiles(w http.ResponseWriter, r *http.Request) {
+	params := GetDeploymentFiles{
+		PagingParams: request.ParsePaging(r),
+		ID:           r.PathValue("id"),
+		Prefix:       r.URL.Query().Get("prefix"),
+	}
+
+	page, err := h.service.GetDeploymentFiles(r.Context(), params)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Deployment not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, page)
+}
+
+// handleGetDeploymentDiff compares the files of a deployment with those of another one.
+// @Summary      Compare the files of two deployments
+// @Tags         deployments
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Param against query string true "ID of the deployment to compare against, e.g. the one in production"
+// @Param patch query bool false "Include unified diffs of text files up to 64 KiB"
+// @Success      200 {object} DeploymentDiff
+// @Failure      404 {object} response.ErrorResponse "Deployment not found"
+// @Failure      409 {object} response.ErrorResponse "Deployment has no recorded files"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/diff [get]
+func (h *handler) handleGetDeploymentDiff(w http.ResponseWriter, r *http.Request) {
+	patch, _ := strconv.ParseBool(r.URL.Query().Get("patch"))
+	params := GetDeploymentDiff{
+		ID:      r.PathValue("id"),
+		Against: r.URL.Query().Get("against"),
+		Patch:   patch,
+	}
+
+	diff, err := h.service.GetDeploymentDiff(r.Context(), params)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Deployment not found")
+			return
+		}
+		if errors.Is(err, ErrFilesNotRecorded) {
+			response.Error(w, http.StatusConflict, "Deployment has no recorded files")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, diff)
+}
+
+// handleUpload uploads a deployment artifact (zip or tar, optionally compressed) with default 30-day TTL.
 // @Summary      Upload a deployment artifact
+// @Description  Archives are limited to 10 MB in one request; larger ones, or uploads over unreliable networks, use POST /deployments/resumable.
 // @Tags         deployments
 // @Accept       mpfd
 // @Produce      json
 // @Param project_id formData string true "Project ID"
 // @Param hash formData string true "Content-addressable hash"
-// @Param entry_path formData string true "URL path prefix for Traefik routing"
-// @Param file formData file true "Binary file (zip or tar.gz)"
+// @Param entry_path formData string false "Entry file (e.g. /index.html) or directory ending with /; defaults to infario.json or /"
+// @Param serving_mode formData string false "Serving mode: spa, static or clean_urls (defaults to infario.json, then the project setting)"
+// @Param file formData file true "Archive (zip, tar, tar.gz, tar.bz2, tar.zst or tar.xz), detected from its content"
 // @Success      201 {object} Deployment
 // @Failure      400 {object} response.ErrorResponse "Invalid request"
 // @Failure      422 {object} response.ErrorResponse "Validation failed"
@@ -95,10 +193,11 @@ func (h *handler) handleUpload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	req := UploadDeployment{
-		ProjectID:  r.FormValue("project_id"),
-		Hash:       r.FormValue("hash"),
-		EntryPath:  r.FormValue("entry_path"),
-		FileUpload: *upload,
+		ProjectID:   r.FormValue("project_id"),
+		Hash:        r.FormValue("hash"),
+		EntryPath:   r.FormValue("entry_path"),
+		ServingMode: r.FormValue("serving_mode"),
+		FileUpload:  *upload,
 	}
 
 	deployment, err := h.service.Upload(r.Context(), req)
@@ -113,3 +212,370 @@ func (h *handler) handleUpload(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, http.StatusCreated, deployment)
 }
+
+// handleCreateDeltaUpload starts a deployment uploaded file by file.
+// @Summary      Start a delta upload
+// @Description  Lists every file of the deployment by SHA-256 digest. The response names the digests the server does not store yet; upload each with PUT /deployments/{id}/blobs/{sha256}, then finalize the deployment. Uploads receiving no content for 24 hours are abandoned and their deployment fails.
+// @Tags         deployments
+// @Accept       json
+// @Produce      json
+// @Param request body CreateDeltaUpload true "Project, hash and file manifest"
+// @Success      201 {object} DeltaUpload
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Failure      501 {object} response.ErrorResponse "Storage does not support delta uploads"
+// @Router       /deployments/delta [post]
+func (h *handler) handleCreateDeltaUpload(w http.ResponseWriter, r *http.Request) {
+	var req CreateDeltaUpload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	upload, err := h.service.CreateDeltaUpload(r.Context(), req)
+	if err != nil {
+		writeDeltaError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, upload)
+}
+
+// handleUploadDeltaBlob uploads one content of a delta upload.
+// @Summary      Upload a content of a delta upload
+// @Tags         deployments
+// @Accept       octet-stream
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Param sha256 path string true "SHA-256 digest of the content"
+// @Success      200 {object} DeltaUpload
+// @Failure      404 {object} response.ErrorResponse "Upload session not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed or content does not match its digest"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/blobs/{sha256} [put]
+func (h *handler) handleUploadDeltaBlob(w http.ResponseWriter, r *http.Request) {
+	req := UploadDeltaBlob{
+		ID:      r.PathValue("id"),
+		SHA256:  r.PathValue("sha256"),
+		Content: r.Body,
+	}
+
+	upload, err := h.service.UploadDeltaBlob(r.Context(), req)
+	if err != nil {
+		writeDeltaError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, upload)
+}
+
+// handleFinalizeDeltaUpload assembles a delta upload and queues it for processing.
+// @Summary      Finalize a delta upload
+// @Tags         deployments
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Success      200 {object} Deployment
+// @Failure      404 {object} response.ErrorResponse "Upload session not found"
+// @Failure      409 {object} response.ErrorResponse "Contents still missing, keyed by digest"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/finalize [post]
+func (h *handler) handleFinalizeDeltaUpload(w http.ResponseWriter, r *http.Request) {
+	deployment, err := h.service.FinalizeDeltaUpload(r.Context(), FinalizeDeltaUpload{ID: r.PathValue("id")})
+	if err != nil {
+		writeDeltaError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, deployment)
+}
+
+// writeDeltaError maps the errors of delta upload requests to responses.
+func writeDeltaError(w http.ResponseWriter, err error) {
+	var limitErr *engine.LimitError
+	var entryErr *engine.EntryError
+	var missingErr *engine.MissingBlobsError
+
+	if fields := response.MapValidationErrorPaths(err); len(fields) > 0 {
+		response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+		return
+	}
+
+	switch {
+	case errors.As(err, &limitErr):
+		response.Error(w, http.StatusUnprocessableEntity, limitErr.Error())
+	case errors.As(err, &entryErr):
+		response.Error(w, http.StatusUnprocessableEntity, entryErr.Error())
+	case errors.Is(err, engine.ErrUnexpectedBlob):
+		response.Error(w, http.StatusUnprocessableEntity, "Content is not part of the upload")
+	case errors.Is(err, engine.ErrDigestMismatch):
+		response.Error(w, http.StatusUnprocessableEntity, "Content does not match its digest and size")
+	case errors.As(err, &missingErr):
+		missing := make(map[string]string, len(missingErr.Digests))
+		for _, digest := range missingErr.Digests {
+			missing[digest] = "Content not uploaded"
+		}
+		response.Error(w, http.StatusConflict, "Upload is missing file contents", missing)
+	case errors.Is(err, pgx.ErrNoRows):
+		response.Error(w, http.StatusNotFound, "Deployment not found")
+	case errors.Is(err, engine.ErrAssemblyNotFound):
+		response.Error(w, http.StatusNotFound, "Upload session not found")
+	case errors.Is(err, engine.ErrBlobsUnsupported):
+		response.Error(w, http.StatusNotImplemented, "Delta uploads require local storage")
+	default:
+		response.Error(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// uploadOffsetHeader carries the offset of a resumable upload chunk, and the bytes received so far in
+// responses.
+const uploadOffsetHeader = "Upload-Offset"
+
+// handleCreateResumableUpload starts a deployment whose archive is sent in chunks.
+// @Summary      Start a resumable upload
+// @Description  Declares the archive's size and SHA-256 checksum. Send the archive in order with PATCH /deployments/{id}/upload, resuming from the offset reported by GET /deployments/{id}/upload after an interruption, then finalize the upload. Uploads receiving nothing for 24 hours are abandoned.
+// @Tags         deployments
+// @Accept       json
+// @Produce      json
+// @Param request body CreateResumableUpload true "Project, hash, archive size and checksum"
+// @Success      201 {object} ResumableUpload
+// @Header       201 {integer} Upload-Offset "Bytes received so far"
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      422 {object} response.ErrorResponse "Validation failed or archive too large"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/resumable [post]
+func (h *handler) handleCreateResumableUpload(w http.ResponseWriter, r *http.Request) {
+	var req CreateResumableUpload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	upload, err := h.service.CreateResumableUpload(r.Context(), req)
+	if err != nil {
+		writeResumableError(w, err)
+		return
+	}
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	response.JSON(w, http.StatusCreated, upload)
+}
+
+// handleGetResumableUpload reports how much of a resumable upload has been received.
+// @Summary      Get a resumable upload
+// @Tags         deployments
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Success      200 {object} ResumableUpload
+// @Header       200 {integer} Upload-Offset "Bytes received so far"
+// @Failure      404 {object} response.ErrorResponse "Upload not found or abandoned"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/upload [get]
+func (h *handler) handleGetResumableUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := h.service.GetResumableUpload(r.Context(), GetResumableUpload{ID: r.PathValue("id")})
+	if err != nil {
+		writeResumableError(w, err)
+		return
+	}
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	response.JSON(w, http.StatusOK, upload)
+}
+
+// handleUploadChunk appends the next chunk of a resumable upload.
+// @Summary      Upload a chunk of a resumable upload
+// @Tags         deployments
+// @Accept       octet-stream
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Param Upload-Offset header integer true "Offset of the chunk, which must equal the bytes received so far"
+// @Success      200 {object} ResumableUpload
+// @Header       200 {integer} Upload-Offset "Bytes received so far"
+// @Failure      400 {object} response.ErrorResponse "Invalid or missing Upload-Offset header"
+// @Failure      404 {object} response.ErrorResponse "Upload not found or abandoned"
+// @Failure      409 {object} response.ErrorResponse "Offset does not match the bytes received, or another request is writing to the upload"
+// @Failure      422 {object} response.ErrorResponse "Validation failed or chunk extends past the declared size"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/upload [patch]
+func (h *handler) handleUploadChunk(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid or missing Upload-Offset header")
+		return
+	}
+
+	req := UploadChunk{
+		ID:      r.PathValue("id"),
+		Offset:  offset,
+		Content: r.Body,
+	}
+
+	upload, err := h.service.UploadChunk(r.Context(), req)
+	if err != nil {
+		writeResumableError(w, err)
+		return
+	}
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	response.JSON(w, http.StatusOK, upload)
+}
+
+// handleFinalizeResumableUpload verifies a fully received archive and queues it for extraction.
+// @Summary      Finalize a resumable upload
+// @Description  A checksum mismatch fails the deployment, since the corrupted bytes cannot be located and resent.
+// @Tags         deployments
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Success      200 {object} Deployment
+// @Failure      404 {object} response.ErrorResponse "Upload not found or abandoned"
+// @Failure      409 {object} response.ErrorResponse "Archive not fully received, or another request is writing to the upload"
+// @Failure      422 {object} response.ErrorResponse "Validation failed or archive does not match its checksum"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/upload/finalize [post]
+func (h *handler) handleFinalizeResumableUpload(w http.ResponseWriter, r *http.Request) {
+	deployment, err := h.service.FinalizeResumableUpload(r.Context(), FinalizeResumableUpload{ID: r.PathValue("id")})
+	if err != nil {
+		writeResumableError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, deployment)
+}
+
+// writeResumableError maps the errors of resumable upload requests to responses.
+func writeResumableError(w http.ResponseWriter, err error) {
+	var limitErr *engine.LimitError
+	var offsetErr *engine.OffsetError
+	var incompleteErr *engine.IncompleteUploadError
+
+	if fields := response.MapValidationErrors(err); len(fields) > 0 {
+		response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+		return
+	}
+
+	switch {
+	case errors.As(err, &limitErr):
+		response.Error(w, http.StatusUnprocessableEntity, limitErr.Error())
+	case errors.Is(err, engine.ErrChunkTooLarge):
+		response.Error(w, http.StatusUnprocessableEntity, "Chunk extends past the declared upload size")
+	case errors.Is(err, engine.ErrChecksumMismatch):
+		response.Error(w, http.StatusUnprocessableEntity, "Uploaded archive does not match its checksum")
+	case errors.As(err, &offsetErr):
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(offsetErr.Offset, 10))
+		response.Error(w, http.StatusConflict, "Chunk must start at offset "+strconv.FormatInt(offsetErr.Offset, 10))
+	case errors.As(err, &incompleteErr):
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(incompleteErr.Offset, 10))
+		response.Error(w, http.StatusConflict, "Archive is not fully uploaded: "+incompleteErr.Error())
+	case errors.Is(err, engine.ErrUploadBusy):
+		response.Error(w, http.StatusConflict, "Another request is writing to the upload")
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, engine.ErrUploadNotFound):
+		response.Error(w, http.StatusNotFound, "Upload not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// handleCreateProxy registers a deployment that proxies to upstreams instead of serving an archive.
+// @Summary      Create a proxy deployment
+// @Description  The deployment becomes ready once every upstream answers its health probe with a status below 500.
+// @Tags         deployments
+// @Accept       json
+// @Produce      json
+// @Param request body CreateProxyDeployment true "Project, hash and upstreams"
+// @Success      201 {object} Deployment
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/proxy [post]
+func (h *handler) handleCreateProxy(w http.ResponseWriter, r *http.Request) {
+	var req CreateProxyDeployment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	deployment, err := h.service.CreateProxy(r.Context(), req)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, deployment)
+}
+
+// handleSetProtection protects a deployment's preview hostname with basic auth.
+// @Summary      Protect a deployment with basic auth
+// @Description  Replaces the deployment's basic auth users, which are required on its preview hostname instead of the project's. The production hostname keeps the project's protection.
+// @Tags         deployments
+// @Accept       json
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Param request body SetDeploymentProtection true "Users"
+// @Success      200 {object} Deployment
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      404 {object} response.ErrorResponse "Deployment not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/protection [put]
+func (h *handler) handleSetProtection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req SetDeploymentProtection
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.ID = id
+
+	deployment, err := h.service.SetDeploymentProtection(r.Context(), req)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Deployment not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, deployment)
+}
+
+// handleRemoveProtection returns a deployment's preview hostname to its project's basic auth.
+// @Summary      Remove basic auth from a deployment
+// @Tags         deployments
+// @Produce      json
+// @Param id path string true "Deployment ID"
+// @Success      200 {object} Deployment
+// @Failure      404 {object} response.ErrorResponse "Deployment not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /deployments/{id}/protection [delete]
+func (h *handler) handleRemoveProtection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	deployment, err := h.service.RemoveDeploymentProtection(r.Context(), RemoveDeploymentProtection{ID: id})
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Deployment not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, deployment)
+}
@@ -2,9 +2,15 @@ package deployment
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
 	"github.com/dimasbaguspm/infario/internal/platform/engine"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
 	"github.com/dimasbaguspm/infario/pkgs/redis"
 	"github.com/dimasbaguspm/infario/pkgs/validator"
 	goredis "github.com/redis/go-redis/v9"
@@ -14,13 +20,15 @@ type Service struct {
 	repo       DeploymentRepository
 	redis      *goredis.Client
 	fileEngine *engine.FileEngine
+	routing    gateway.Routing
 }
 
-func NewService(repo DeploymentRepository, redisClient *goredis.Client, fileEngine *engine.FileEngine) *Service {
+func NewService(repo DeploymentRepository, redisClient *goredis.Client, fileEngine *engine.FileEngine, routing gateway.Routing) *Service {
 	return &Service{
 		repo:       repo,
 		redis:      redisClient,
 		fileEngine: fileEngine,
+		routing:    routing,
 	}
 }
 
@@ -32,6 +40,7 @@ func (s *Service) GetDeploymentByID(ctx context.Context, d GetSingleDeployment)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
 	}
+	s.setURL(resp)
 	return resp, nil
 }
 
@@ -43,9 +52,26 @@ func (s *Service) GetPagedDeployments(ctx context.Context, params GetPagedDeploy
 	if err != nil {
 		return nil, fmt.Errorf("Failed to list deployments: %w", err)
 	}
+	for i := range page.Items {
+		s.setURL(&page.Items[i])
+	}
 	return page, nil
 }
 
+// setURL fills in the public URLs of a deployment whose project still exists.
+func (s *Service) setURL(d *Deployment) {
+	if d.ProjectName == nil {
+		return
+	}
+
+	d.PreviewURL = s.routing.PreviewURL(d.PreviewID, *d.ProjectName)
+	d.URL = d.PreviewURL
+	if d.Production {
+		d.ProductionURL = s.routing.ProductionURL(*d.ProjectName)
+		d.URL = d.ProductionURL
+	}
+}
+
 func (s *Service) Upload(ctx context.Context, d UploadDeployment) (*Deployment, error) {
 	if err := validator.Validate.Struct(d); err != nil {
 		return nil, fmt.Errorf("Validation failed: %w", err)
@@ -58,35 +84,399 @@ func (s *Service) Upload(ctx context.Context, d UploadDeployment) (*Deployment,
 
 	// Extract uploaded archive asynchronously
 	go func() {
-		destPath := "deployments/" + d.ProjectID + "/" + ID
-
-		// Open and extract the file
 		file, err := d.File.Open()
 		if err != nil {
 			fmt.Printf("failed to open upload file for extraction: %v\n", err)
+			s.failUpload(ID, "Failed to read the uploaded archive")
 			return
 		}
 		defer file.Close()
 
-		err = s.fileEngine.Extract(context.Background(), destPath, file, d.File.Filename)
-		if err != nil {
-			fmt.Printf("failed to extract deployment file: %v\n", err)
-			return
+		s.extractArchive(ID, d.ProjectID, file, d.File.Filename)
+	}()
+
+	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: ID})
+}
+
+// extractArchive extracts the uploaded archive of a deployment, records its files and queues it for
+// processing. Failures mark the deployment as failed.
+func (s *Service) extractArchive(ID, projectID string, archive io.Reader, filename string) {
+	destPath := "deployments/" + projectID + "/" + ID
+
+	formats, err := s.repo.GetArchiveFormats(context.Background(), projectID)
+	if err != nil {
+		fmt.Printf("failed to get project archive formats: %v\n", err)
+		s.failUpload(ID, "Failed to read the project's archive settings")
+		return
+	}
+	allowed := make([]engine.Format, len(formats))
+	for i, format := range formats {
+		allowed[i] = engine.Format(format)
+	}
+
+	files, err := s.fileEngine.Extract(context.Background(), destPath, archive, filename, allowed)
+	if err != nil {
+		fmt.Printf("failed to extract deployment file: %v\n", err)
+		var limitErr *engine.LimitError
+		var entryErr *engine.EntryError
+		var formatErr *engine.FormatError
+		if errors.As(err, &limitErr) {
+			s.failUpload(ID, limitErr.Error())
+		} else if errors.As(err, &entryErr) {
+			s.failUpload(ID, entryErr.Error())
+		} else if errors.As(err, &formatErr) {
+			s.failUpload(ID, formatErr.Error())
+		} else {
+			s.failUpload(ID, "Failed to extract the uploaded archive")
+		}
+		return
+	}
+
+	if err := s.repo.SaveFiles(context.Background(), ID, toDeploymentFiles(files)); err != nil {
+		fmt.Printf("failed to save deployment files: %v\n", err)
+		s.failUpload(ID, "Failed to record the deployment's files")
+		return
+	}
+
+	// After extraction, emit task to Redis for validation and Traefik config
+	task := DeploymentTask{
+		Deployment: &Deployment{
+			ID: ID,
+		},
+		OriginalName: filename,
+	}
+	if err := redis.Emit(context.Background(), s.redis, QueueKey, task); err != nil {
+		fmt.Printf("failed to emit deployment task: %v\n", err)
+		s.failUpload(ID, "Failed to queue the deployment for processing")
+	}
+}
+
+// toDeploymentFiles converts an extraction manifest to its API representation.
+func toDeploymentFiles(entries []engine.FileEntry) []DeploymentFile {
+	files := make([]DeploymentFile, len(entries))
+	for i, e := range entries {
+		files[i] = DeploymentFile{
+			Path:        e.Path,
+			Size:        e.Size,
+			Mode:        fmt.Sprintf("%04o", uint32(e.Mode)),
+			SHA256:      e.SHA256,
+			ContentType: e.ContentType,
+		}
+	}
+	return files
+}
+
+// failUpload marks a deployment whose archive could not be extracted as failed.
+func (s *Service) failUpload(id, reason string) {
+	if err := s.repo.UpdateStatus(context.Background(), UpdateDeploymentStatus{
+		ID:      id,
+		Status:  StatusError,
+		Message: reason,
+	}); err != nil {
+		fmt.Printf("failed to mark deployment as failed: %v\n", err)
+	}
+}
+
+// CreateProxy registers a deployment served by upstreams and queues it for its health probe.
+func (s *Service) CreateProxy(ctx context.Context, d CreateProxyDeployment) (*Deployment, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	ID, err := s.repo.CreateProxy(ctx, d)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create deployment record: %w", err)
+	}
+
+	task := DeploymentTask{Deployment: &Deployment{ID: ID}}
+	if err := redis.Emit(ctx, s.redis, QueueKey, task); err != nil {
+		return nil, fmt.Errorf("Failed to queue deployment: %w", err)
+	}
+
+	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: ID})
+}
+
+// CreateDeltaUpload starts a deployment uploaded file by file. Contents already stored for other
+// deployments are reused; the others are returned as missing, to be uploaded before finalizing.
+func (s *Service) CreateDeltaUpload(ctx context.Context, d CreateDeltaUpload) (*DeltaUpload, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	files := toAssemblyFiles(d.Files)
+	if err := s.fileEngine.CheckAssembly(files); err != nil {
+		return nil, fmt.Errorf("Failed to check delta upload: %w", err)
+	}
+
+	ID, err := s.repo.Upload(ctx, UploadDeployment{
+		ProjectID:   d.ProjectID,
+		Hash:        d.Hash,
+		EntryPath:   d.EntryPath,
+		ServingMode: d.ServingMode,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create deployment record: %w", err)
+	}
+
+	missing, err := s.fileEngine.BeginAssembly(ctx, ID, files)
+	if err != nil {
+		s.failUpload(ID, "Failed to start the upload")
+		return nil, fmt.Errorf("Failed to start delta upload: %w", err)
+	}
+	return s.deltaUpload(ctx, ID, missing)
+}
+
+// UploadDeltaBlob stores one content of a delta upload after checking it against its digest.
+func (s *Service) UploadDeltaBlob(ctx context.Context, d UploadDeltaBlob) (*DeltaUpload, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	missing, err := s.fileEngine.AddBlob(ctx, d.ID, d.SHA256, d.Content)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to store delta upload content: %w", err)
+	}
+	return s.deltaUpload(ctx, d.ID, missing)
+}
+
+// FinalizeDeltaUpload assembles a delta upload once every content is uploaded and queues it like an
+// uploaded archive.
+func (s *Service) FinalizeDeltaUpload(ctx context.Context, d FinalizeDeltaUpload) (*Deployment, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	dep, err := s.repo.GetByID(ctx, GetSingleDeployment{ID: d.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
+	}
+
+	destPath := "deployments/" + dep.ProjectID + "/" + dep.ID
+	files, err := s.fileEngine.FinishAssembly(ctx, dep.ID, destPath)
+	if err != nil {
+		// Missing contents can still be uploaded; anything else leaves the upload unusable
+		var missingErr *engine.MissingBlobsError
+		if !errors.As(err, &missingErr) && !errors.Is(err, engine.ErrAssemblyNotFound) {
+			s.failUpload(dep.ID, "Failed to assemble the uploaded files")
 		}
+		return nil, fmt.Errorf("Failed to finalize delta upload: %w", err)
+	}
+
+	if err := s.repo.SaveFiles(ctx, dep.ID, toDeploymentFiles(files)); err != nil {
+		s.failUpload(dep.ID, "Failed to record the deployment's files")
+		return nil, fmt.Errorf("Failed to save deployment files: %w", err)
+	}
+
+	task := DeploymentTask{Deployment: &Deployment{ID: dep.ID}}
+	if err := redis.Emit(ctx, s.redis, QueueKey, task); err != nil {
+		// The assembly is consumed, so the deployment can never be queued again
+		s.failUpload(dep.ID, "Failed to queue the deployment for processing")
+		return nil, fmt.Errorf("Failed to queue deployment: %w", err)
+	}
+
+	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: dep.ID})
+}
+
+// deltaUpload returns the state of a delta upload.
+func (s *Service) deltaUpload(ctx context.Context, id string, missing []string) (*DeltaUpload, error) {
+	dep, err := s.GetDeploymentByID(ctx, GetSingleDeployment{ID: id})
+	if err != nil {
+		return nil, err
+	}
+	return &DeltaUpload{Deployment: dep, Missing: missing}, nil
+}
+
+// toAssemblyFiles converts a delta upload manifest to the files the engine assembles.
+func toAssemblyFiles(files []DeltaFile) []engine.AssemblyFile {
+	out := make([]engine.AssemblyFile, len(files))
+	for i, f := range files {
+		mode := uint64(0644)
+		if f.Mode != "" {
+			mode, _ = strconv.ParseUint(f.Mode, 8, 32)
+		}
+		out[i] = engine.AssemblyFile{
+			Path:   f.Path,
+			SHA256: f.SHA256,
+			Size:   f.Size,
+			Mode:   fs.FileMode(mode),
+		}
+	}
+	return out
+}
+
+// CreateResumableUpload starts a deployment whose archive is sent in chunks, so an interrupted
+// transfer resumes from the bytes already received.
+func (s *Service) CreateResumableUpload(ctx context.Context, d CreateResumableUpload) (*ResumableUpload, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if err := s.fileEngine.CheckUpload(d.Size); err != nil {
+		return nil, fmt.Errorf("Failed to check resumable upload: %w", err)
+	}
+
+	ID, err := s.repo.Upload(ctx, UploadDeployment{
+		ProjectID:   d.ProjectID,
+		Hash:        d.Hash,
+		EntryPath:   d.EntryPath,
+		ServingMode: d.ServingMode,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create deployment record: %w", err)
+	}
+
+	if err := s.fileEngine.CreateUpload(ctx, ID, d.Size); err != nil {
+		s.failUpload(ID, "Failed to start the upload")
+		return nil, fmt.Errorf("Failed to start resumable upload: %w", err)
+	}
+	if err := s.repo.CreateUpload(ctx, ID, d); err != nil {
+		s.fileEngine.RemoveUpload(ctx, ID)
+		s.failUpload(ID, "Failed to start the upload")
+		return nil, fmt.Errorf("Failed to create upload record: %w", err)
+	}
+
+	return s.GetResumableUpload(ctx, GetResumableUpload{ID: ID})
+}
+
+// GetResumableUpload returns how much of a resumable upload has been received.
+func (s *Service) GetResumableUpload(ctx context.Context, d GetResumableUpload) (*ResumableUpload, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
 
-		// After extraction, emit task to Redis for validation and Traefik config
-		task := DeploymentTask{
-			Deployment: &Deployment{
-				ID: ID,
-			},
-			OriginalName: d.File.Filename,
+	upload, err := s.repo.GetUpload(ctx, d)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get upload by id: %w", err)
+	}
+	upload.Offset, err = s.fileEngine.UploadOffset(ctx, d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get upload offset: %w", err)
+	}
+	upload.Deployment, err = s.GetDeploymentByID(ctx, GetSingleDeployment{ID: d.ID})
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// UploadChunk appends the next chunk of a resumable upload. Bytes received before the transfer
+// broke are kept, so the client resumes from the offset reported by GetResumableUpload.
+func (s *Service) UploadChunk(ctx context.Context, d UploadChunk) (*ResumableUpload, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	if _, err := s.repo.GetUpload(ctx, GetResumableUpload{ID: d.ID}); err != nil {
+		return nil, fmt.Errorf("Failed to get upload by id: %w", err)
+	}
+	if _, err := s.fileEngine.WriteChunk(ctx, d.ID, d.Offset, d.Content); err != nil {
+		return nil, fmt.Errorf("Failed to store upload chunk: %w", err)
+	}
+	if err := s.repo.TouchUpload(ctx, d.ID); err != nil {
+		return nil, fmt.Errorf("Failed to extend upload: %w", err)
+	}
+
+	return s.GetResumableUpload(ctx, GetResumableUpload{ID: d.ID})
+}
+
+// FinalizeResumableUpload verifies a fully received archive against the checksum declared when the
+// upload started and extracts it like an archive uploaded in one request.
+func (s *Service) FinalizeResumableUpload(ctx context.Context, d FinalizeResumableUpload) (*Deployment, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	upload, err := s.repo.GetUpload(ctx, GetResumableUpload{ID: d.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get upload by id: %w", err)
+	}
+	dep, err := s.repo.GetByID(ctx, GetSingleDeployment{ID: d.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
+	}
+
+	archive, err := s.fileEngine.CompleteUpload(ctx, d.ID, upload.SHA256)
+	if errors.Is(err, engine.ErrChecksumMismatch) {
+		// The received bytes are wrong but unknown where, so the upload cannot be resumed
+		s.fileEngine.RemoveUpload(ctx, d.ID)
+		if _, err := s.repo.DeleteUpload(ctx, d.ID); err != nil {
+			fmt.Printf("failed to delete upload record: %v\n", err)
 		}
-		if err := redis.Emit(context.Background(), s.redis, QueueKey, task); err != nil {
-			fmt.Printf("failed to emit deployment task: %v\n", err)
+		s.failUpload(d.ID, "Uploaded archive does not match its checksum")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to finalize resumable upload: %w", err)
+	}
+
+	// Claim the upload so the cleanup worker cannot abandon it meanwhile
+	claimed, err := s.repo.DeleteUpload(ctx, d.ID)
+	if err != nil || !claimed {
+		archive.Close()
+		if err == nil {
+			err = engine.ErrUploadNotFound
 		}
+		return nil, fmt.Errorf("Failed to finalize resumable upload: %w", err)
+	}
+
+	go func() {
+		defer archive.Close()
+		s.extractArchive(dep.ID, dep.ProjectID, archive, upload.Filename)
 	}()
 
-	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: ID})
+	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: dep.ID})
+}
+
+// GetDeploymentFiles lists the files recorded when a deployment was extracted.
+func (s *Service) GetDeploymentFiles(ctx context.Context, params GetDeploymentFiles) (*DeploymentFilePaged, error) {
+	if err := validator.Validate.Struct(params); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if _, err := s.repo.GetByID(ctx, GetSingleDeployment{ID: params.ID}); err != nil {
+		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
+	}
+	page, err := s.repo.GetFiles(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list deployment files: %w", err)
+	}
+	return page, nil
+}
+
+// GetDeploymentDiff compares the files of a deployment with those of another one, optionally with
+// unified diffs of the text files that changed.
+func (s *Service) GetDeploymentDiff(ctx context.Context, params GetDeploymentDiff) (*DeploymentDiff, error) {
+	if err := validator.Validate.Struct(params); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	newDep, err := s.repo.GetByID(ctx, GetSingleDeployment{ID: params.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
+	}
+	oldDep, err := s.repo.GetByID(ctx, GetSingleDeployment{ID: params.Against})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
+	}
+	if newDep.FileCount == nil || oldDep.FileCount == nil {
+		return nil, fmt.Errorf("Failed to compare deployments: %w", ErrFilesNotRecorded)
+	}
+
+	newFiles, err := s.repo.ListFiles(ctx, newDep.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list deployment files: %w", err)
+	}
+	oldFiles, err := s.repo.ListFiles(ctx, oldDep.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list deployment files: %w", err)
+	}
+
+	diff := compareFiles(oldFiles, newFiles)
+	diff.ID = newDep.ID
+	diff.Against = oldDep.ID
+	if params.Patch {
+		if err := s.addPatches(ctx, &diff, oldDep, newDep); err != nil {
+			return nil, fmt.Errorf("Failed to diff deployment files: %w", err)
+		}
+	}
+	return &diff, nil
 }
 
 func (s *Service) UpdateDeploymentStatus(ctx context.Context, d UpdateDeploymentStatus) (*Deployment, error) {
@@ -99,3 +489,56 @@ func (s *Service) UpdateDeploymentStatus(ctx context.Context, d UpdateDeployment
 	}
 	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: d.ID})
 }
+
+func (s *Service) SetDeploymentProtection(ctx context.Context, d SetDeploymentProtection) (*Deployment, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	dep, err := s.repo.GetByID(ctx, GetSingleDeployment{ID: d.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
+	}
+
+	credentials := make([]gateway.Credential, len(d.Credentials))
+	for i, c := range d.Credentials {
+		hash, err := gateway.HashPassword(c.Password)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to hash password: %w", err)
+		}
+		credentials[i] = gateway.Credential{Username: c.Username, PasswordHash: hash}
+	}
+
+	if err := s.repo.SetProtection(ctx, d, credentials); err != nil {
+		return nil, fmt.Errorf("Failed to set deployment protection: %w", err)
+	}
+	if err := s.requestSync(ctx, dep.ProjectID); err != nil {
+		return nil, err
+	}
+	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: d.ID})
+}
+
+func (s *Service) RemoveDeploymentProtection(ctx context.Context, d RemoveDeploymentProtection) (*Deployment, error) {
+	if err := validator.Validate.Struct(d); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	dep, err := s.repo.GetByID(ctx, GetSingleDeployment{ID: d.ID})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get deployment by id: %w", err)
+	}
+
+	if err := s.repo.RemoveProtection(ctx, d); err != nil {
+		return nil, fmt.Errorf("Failed to remove deployment protection: %w", err)
+	}
+	if err := s.requestSync(ctx, dep.ProjectID); err != nil {
+		return nil, err
+	}
+	return s.GetDeploymentByID(ctx, GetSingleDeployment{ID: d.ID})
+}
+
+// requestSync queues a gateway config regeneration for the deployment's project.
+func (s *Service) requestSync(ctx context.Context, projectID string) error {
+	if err := redis.Emit(ctx, s.redis, project.SyncQueueKey, project.SyncTask{ProjectID: projectID}); err != nil {
+		return fmt.Errorf("Failed to queue gateway sync: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package deployment
+
+import (
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	playground "github.com/go-playground/validator/v10"
+)
+
+// RegisterValidations adds the struct-level checks of proxy deployments and manifests to v: their
+// upstreams must not overlap, and only proxy deployments may proxy the / path.
+func RegisterValidations(v *playground.Validate) {
+	v.RegisterStructValidation(func(sl playground.StructLevel) {
+		d := sl.Current().Interface().(CreateProxyDeployment)
+		if err := gateway.ValidateUpstreams(d.Upstreams); err != nil {
+			sl.ReportError(d.Upstreams, "upstreams", "Upstreams", "rule", err.Error())
+		} else if !gateway.ProxiesSite(d.Upstreams) {
+			sl.ReportError(d.Upstreams, "upstreams", "Upstreams", "rule", "one upstream must proxy the / path")
+		}
+	}, CreateProxyDeployment{})
+
+	v.RegisterStructValidation(func(sl playground.StructLevel) {
+		m := sl.Current().Interface().(Manifest)
+		if err := gateway.ValidateUpstreams(m.Upstreams); err != nil {
+			sl.ReportError(m.Upstreams, "upstreams", "Upstreams", "rule", err.Error())
+		} else if gateway.ProxiesSite(m.Upstreams) {
+			sl.ReportError(m.Upstreams, "upstreams", "Upstreams", "rule", "archives cannot proxy the / path; create a proxy deployment instead")
+		}
+	}, Manifest{})
+}
@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dimasbaguspm/infario/internal/platform/engine"
+	"github.com/dimasbaguspm/infario/internal/platform/scheduler"
+	"github.com/dimasbaguspm/infario/internal/resources/deployment"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DeltaCleanupInterval defines how often to check for abandoned delta uploads
+	DeltaCleanupInterval = 15 * time.Minute
+	// DeltaCleanupConcurrency limits concurrent cleanup workers
+	DeltaCleanupConcurrency = 5
+)
+
+// StartDeltaCleanup initializes and starts the abandoned delta upload cleanup worker.
+// This periodically removes the files assembled for delta uploads that stopped receiving contents,
+// freeing the contents only they used, and marks their deployments as failed.
+func StartDeltaCleanup(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	fileEngine *engine.FileEngine,
+	logger *slog.Logger,
+) {
+	repo := deployment.NewPostgresRepository(db)
+
+	// Define retriever: assemblies idle for longer than a delta upload session lasts
+	retriever := func(ctx context.Context) ([]string, error) {
+		return fileEngine.StaleAssemblies(deployment.DeltaUploadTTL)
+	}
+
+	// Define executor: remove the assembly, then fail the deployment it was for
+	executor := func(ctx context.Context, id string) error {
+		// Busy assemblies are receiving content after all and are retried on the next run. Finalized
+		// ones are already gone and leave their deployment alone.
+		removed, err := fileEngine.RemoveAssembly(ctx, id)
+		if err != nil || !removed {
+			return err
+		}
+
+		return repo.UpdateStatus(ctx, deployment.UpdateDeploymentStatus{
+			ID:      id,
+			Status:  deployment.StatusError,
+			Message: "Upload was abandoned before it was finalized",
+		})
+	}
+
+	// Define error handler for executor failures
+	onError := func(id string, err error) {
+		if logger != nil {
+			logger.Error("delta upload cleanup failed", "id", id, "err", err)
+		}
+	}
+
+	// Create and start the maintenance runner
+	runner := scheduler.NewMaintenanceRunner(
+		DeltaCleanupInterval,
+		DeltaCleanupConcurrency,
+		retriever,
+		executor,
+		onError,
+		logger,
+	)
+
+	if logger != nil {
+		logger.InfoContext(ctx, "delta upload cleanup worker started", "interval", DeltaCleanupInterval, "concurrency", DeltaCleanupConcurrency)
+	}
+	go runner.Start(ctx)
+}
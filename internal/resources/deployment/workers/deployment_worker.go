@@ -5,13 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/dimasbaguspm/infario/internal/gateway"
 	"github.com/dimasbaguspm/infario/internal/platform/engine"
 	"github.com/dimasbaguspm/infario/internal/resources/deployment"
-	"github.com/dimasbaguspm/infario/pkgs/request"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
@@ -32,6 +34,7 @@ func StartDeploymentConsumer(
 ) {
 	go func() {
 		repo := deployment.NewPostgresRepository(db)
+		projectRepo := project.NewPostgresRepository(db)
 
 		if logger != nil {
 			logger.InfoContext(ctx, "deployment consumer started", "max_workers", MaxConcurrentWorkers)
@@ -85,16 +88,18 @@ func StartDeploymentConsumer(
 			wg.Add(1)
 
 			// Process task in goroutine
-			go processDeploymentTask(ctx, &task, repo, tg, fileEngine, sem, &wg, logger)
+			go processDeploymentTask(ctx, &task, repo, projectRepo, tg, fileEngine, sem, &wg, logger)
 		}
 	}()
 }
 
-// processDeploymentTask validates extracted deployment files and regenerates Traefik config.
+// processDeploymentTask validates extracted deployment files, or probes the upstreams of a proxy
+// deployment, and regenerates the nginx config.
 func processDeploymentTask(
 	ctx context.Context,
 	task *deployment.DeploymentTask,
 	repo deployment.DeploymentRepository,
+	projectRepo project.ProjectRepository,
 	tg *gateway.NginxGateway,
 	fileEngine *engine.FileEngine,
 	sem chan struct{},
@@ -117,40 +122,99 @@ func processDeploymentTask(
 		logger.InfoContext(ctx, "processing deployment task", "deployment_id", dep.ID, "entry_path", dep.EntryPath)
 	}
 
+	// Proxy deployments have no files; they go live once their upstreams answer
+	if dep.Type == deployment.TypeProxy {
+		if err := probeUpstreams(ctx, dep.Upstreams); err != nil {
+			failDeployment(ctx, repo, dep.ID, err.Error(), logger)
+			return
+		}
+		activateDeployment(ctx, dep, repo, projectRepo, fileEngine, tg, logger)
+		return
+	}
+
 	// Define deployment directory where files were extracted
-	deploymentDir := "deployments/" + dep.ProjectID + "/" + dep.ID
+	deploymentDir := deploymentPath(*dep)
 
-	// Validate that entry_path was provided at upload time
-	if dep.EntryPath == "" {
-		if logger != nil {
-			logger.ErrorContext(ctx, "entry_path is required but was not provided at upload", "id", dep.ID)
-		}
-		if err := repo.UpdateStatus(ctx, deployment.UpdateDeploymentStatus{
-			ID:     dep.ID,
-			Status: deployment.StatusError,
-		}); err != nil && logger != nil {
-			logger.ErrorContext(ctx, "failed to update deployment status to error", "id", dep.ID, "error", err)
-		}
+	// The optional infario.json at the archive root provides defaults for settings not given at upload
+	manifest, err := readManifest(fileEngine, path.Join(deploymentDir, deployment.ManifestFile))
+	if err != nil {
+		failDeployment(ctx, repo, dep.ID, err.Error(), logger)
 		return
 	}
 
+	entryPath := dep.EntryPath
+	if entryPath == "" {
+		entryPath = manifest.EntryPath
+	}
+	if entryPath == "" {
+		entryPath = "/"
+	}
+
 	// Verify that entry_path exists within the extracted files
 	// entry_path can be a file (e.g., "/index.html") or directory (e.g., "/app")
-	entryPathFull := deploymentDir + dep.EntryPath
-	fmt.Printf("Validating entry_path for deployment %s: checking existence of %s\n", dep.ID, entryPathFull)
+	entryPathFull := deploymentDir + entryPath
 	if !fileEngine.Exists(ctx, entryPathFull) {
-		if logger != nil {
-			logger.ErrorContext(ctx, "entry_path not found in extracted archive", "id", dep.ID, "entry_path", dep.EntryPath, "full_path", entryPathFull)
-		}
-		if err := repo.UpdateStatus(ctx, deployment.UpdateDeploymentStatus{
-			ID:     dep.ID,
-			Status: deployment.StatusError,
-		}); err != nil && logger != nil {
-			logger.ErrorContext(ctx, "failed to update deployment status to error", "id", dep.ID, "error", err)
-		}
+		failDeployment(ctx, repo, dep.ID, fmt.Sprintf("entry_path %s not found in extracted archive", entryPath), logger)
+		return
+	}
+
+	// Directories are stored with a trailing slash so the gateway never has to guess the entry kind
+	if fileEngine.IsDir(ctx, entryPathFull) && !strings.HasSuffix(entryPath, "/") {
+		entryPath += "/"
+	}
+
+	servingMode, err := resolveServingMode(ctx, dep, manifest, entryPath, projectRepo)
+	if err != nil {
+		failDeployment(ctx, repo, dep.ID, err.Error(), logger)
+		return
+	}
+
+	// Redirect and header rules live in the document root, next to the entry file
+	root := path.Join(deploymentDir, documentRoot(entryPath))
+	redirects, err := readRedirects(fileEngine, path.Join(root, "_redirects"))
+	if err != nil {
+		failDeployment(ctx, repo, dep.ID, err.Error(), logger)
+		return
+	}
+
+	headers, err := readHeaders(fileEngine, path.Join(root, "_headers"))
+	if err != nil {
+		failDeployment(ctx, repo, dep.ID, err.Error(), logger)
+		return
+	}
+
+	if err := probeUpstreams(ctx, manifest.Upstreams); err != nil {
+		failDeployment(ctx, repo, dep.ID, err.Error(), logger)
+		return
+	}
+
+	if err := repo.Configure(ctx, deployment.ConfigureDeployment{
+		ID:          dep.ID,
+		EntryPath:   entryPath,
+		ServingMode: servingMode,
+		Redirects:   append(redirects, manifest.Redirects...),
+		Headers:     append(headers, manifest.Headers...),
+		TTL:         manifest.TTL,
+		Metadata:    manifest.Metadata,
+		Upstreams:   manifest.Upstreams,
+	}); err != nil {
+		failDeployment(ctx, repo, dep.ID, err.Error(), logger)
 		return
 	}
 
+	activateDeployment(ctx, dep, repo, projectRepo, fileEngine, tg, logger)
+}
+
+// activateDeployment marks a processed deployment as ready and regenerates its project's nginx config.
+func activateDeployment(
+	ctx context.Context,
+	dep *deployment.Deployment,
+	repo deployment.DeploymentRepository,
+	projectRepo project.ProjectRepository,
+	fileEngine *engine.FileEngine,
+	tg *gateway.NginxGateway,
+	logger *slog.Logger,
+) {
 	// Update status to "ready"
 	if err := repo.UpdateStatus(ctx, deployment.UpdateDeploymentStatus{
 		ID:     dep.ID,
@@ -162,35 +226,10 @@ func processDeploymentTask(
 		return
 	}
 
-	// Regenerate Traefik config
+	// Regenerate nginx config
 	if tg != nil {
-		status := deployment.StatusReady
-		readyDeps, err := repo.GetPaged(ctx, deployment.GetPagedDeployment{
-			PagingParams: request.PagingParams{PageNumber: 1, PageSize: 100},
-			ProjectID:    &dep.ProjectID,
-			Status:       &status,
-		})
-		if err == nil && len(readyDeps.Items) > 0 {
-			deps := make([]gateway.GatewayDeployment, len(readyDeps.Items))
-			for i, d := range readyDeps.Items {
-				projectName := ""
-				if d.ProjectName != nil {
-					projectName = *d.ProjectName
-				}
-				entryPath := d.EntryPath
-				deps[i] = gateway.GatewayDeployment{
-					ID:          d.ID,
-					Hash:        d.Hash,
-					ProjectID:   d.ProjectID,
-					ProjectName: projectName,
-					EntryPath:   &entryPath,
-				}
-			}
-			if readyDeps.Items[0].ProjectName != nil {
-				if err := tg.WriteProjectConfig(dep.ProjectID, *readyDeps.Items[0].ProjectName, deps); err != nil && logger != nil {
-					logger.ErrorContext(ctx, "failed to write traefik config", "error", err)
-				}
-			}
+		if err := syncProjectConfig(ctx, repo, projectRepo, fileEngine, tg, dep.ProjectID); err != nil && logger != nil {
+			logger.ErrorContext(ctx, "failed to write nginx config", "error", err)
 		}
 	}
 
@@ -198,3 +237,17 @@ func processDeploymentTask(
 		logger.InfoContext(ctx, "deployment task completed", "deployment_id", dep.ID)
 	}
 }
+
+// failDeployment marks a deployment as errored with a reason surfaced through the API.
+func failDeployment(ctx context.Context, repo deployment.DeploymentRepository, id, reason string, logger *slog.Logger) {
+	if logger != nil {
+		logger.ErrorContext(ctx, "deployment failed", "id", id, "reason", reason)
+	}
+	if err := repo.UpdateStatus(ctx, deployment.UpdateDeploymentStatus{
+		ID:      id,
+		Status:  deployment.StatusError,
+		Message: reason,
+	}); err != nil && logger != nil {
+		logger.ErrorContext(ctx, "failed to update deployment status to error", "id", id, "error", err)
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/dimasbaguspm/infario/internal/platform/engine"
 	"github.com/dimasbaguspm/infario/internal/platform/scheduler"
 	"github.com/dimasbaguspm/infario/internal/resources/deployment"
-	"github.com/dimasbaguspm/infario/pkgs/request"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -30,6 +30,7 @@ func StartExpiryCleanup(
 	logger *slog.Logger,
 ) {
 	repo := deployment.NewPostgresRepository(db)
+	projectRepo := project.NewPostgresRepository(db)
 
 	retriever := func(ctx context.Context) ([]*deployment.Deployment, error) {
 		deployments, err := repo.GetExpired(ctx)
@@ -45,14 +46,34 @@ func StartExpiryCleanup(
 
 	// Define executor: clean up physical files and mark as expired
 	executor := func(ctx context.Context, d *deployment.Deployment) error {
-		// Physical cleanup via FileEngine
-		path := "deployments/" + d.ProjectID + "/" + d.Hash
+		// Physical cleanup via FileEngine, including nginx's mirror when storage is remote
+		path := deploymentPath(*d)
 		if err := fileEngine.Remove(ctx, path); err != nil {
 			if logger != nil {
 				logger.Error("failed to remove deployment files", "id", d.ID, "err", err)
 			}
 			return err
 		}
+		if tg != nil {
+			if err := fileEngine.RemoveMirror(ctx, path, tg.StorageDir()); err != nil && logger != nil {
+				logger.Error("failed to remove deployment mirror", "id", d.ID, "err", err)
+			}
+		}
+
+		// Free the contents no other deployment shares; a failure only delays it to the next sweep
+		if files, err := repo.ListFiles(ctx, d.ID); err != nil {
+			if logger != nil {
+				logger.Error("failed to list deployment files", "id", d.ID, "err", err)
+			}
+		} else {
+			digests := make([]string, len(files))
+			for i, f := range files {
+				digests[i] = f.SHA256
+			}
+			if _, err := fileEngine.ReleaseBlobs(ctx, digests); err != nil && logger != nil {
+				logger.Error("failed to release deployment file contents", "id", d.ID, "err", err)
+			}
+		}
 
 		// Logical cleanup via Repository
 		if err := repo.UpdateStatus(ctx, deployment.UpdateDeploymentStatus{
@@ -62,37 +83,10 @@ func StartExpiryCleanup(
 			return err
 		}
 
-		// Regenerate Traefik config after marking deployment as expired
+		// Regenerate nginx config after marking deployment as expired
 		if tg != nil {
-			status := deployment.StatusReady
-			readyDeps, err := repo.GetPaged(ctx, deployment.GetPagedDeployment{
-				PagingParams: request.PagingParams{PageNumber: 1, PageSize: 100},
-				ProjectID:    &d.ProjectID,
-				Status:       &status,
-			})
-			if err == nil && len(readyDeps.Items) > 0 {
-				// Convert to GatewayDeployment format
-				deps := make([]gateway.GatewayDeployment, len(readyDeps.Items))
-				for i, rd := range readyDeps.Items {
-					projectName := ""
-					if rd.ProjectName != nil {
-						projectName = *rd.ProjectName
-					}
-					entryPath := rd.EntryPath
-					deps[i] = gateway.GatewayDeployment{
-						ID:          rd.ID,
-						Hash:        rd.Hash,
-						ProjectID:   rd.ProjectID,
-						ProjectName: projectName,
-						EntryPath:   &entryPath,
-					}
-				}
-				if readyDeps.Items[0].ProjectName != nil {
-					tg.WriteProjectConfig(d.ProjectID, *readyDeps.Items[0].ProjectName, deps)
-				}
-			} else if err == nil {
-				// No ready deployments left, remove config
-				tg.RemoveProjectConfig(d.ProjectID)
+			if err := syncProjectConfig(ctx, repo, projectRepo, fileEngine, tg, d.ProjectID); err != nil && logger != nil {
+				logger.Error("failed to write nginx config", "project_id", d.ProjectID, "err", err)
 			}
 		}
 
@@ -0,0 +1,94 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/internal/platform/engine"
+	"github.com/dimasbaguspm/infario/internal/resources/deployment"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
+	"github.com/dimasbaguspm/infario/pkgs/request"
+	"github.com/jackc/pgx/v5"
+)
+
+// syncProjectConfig regenerates the nginx config of a project from its settings and ready deployments.
+// The config is removed once the project is deleted or has no ready deployment left.
+// Files of ready static deployments are mirrored to nginx's storage directory first, which is a no-op
+// when deployments are already stored there.
+func syncProjectConfig(
+	ctx context.Context,
+	repo deployment.DeploymentRepository,
+	projectRepo project.ProjectRepository,
+	fileEngine *engine.FileEngine,
+	tg *gateway.NginxGateway,
+	projectID string,
+) error {
+	p, err := projectRepo.GetByID(ctx, project.GetSingleProject{ID: projectID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tg.RemoveProjectConfig(projectID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch project: %w", err)
+	}
+
+	readyDeps, err := readyDeployments(ctx, repo, projectID)
+	if err != nil {
+		return err
+	}
+
+	deps := make([]gateway.GatewayDeployment, len(readyDeps))
+	for i, d := range readyDeps {
+		if d.Type != deployment.TypeProxy {
+			if err := fileEngine.Mirror(ctx, deploymentPath(d), tg.StorageDir()); err != nil {
+				return fmt.Errorf("failed to mirror deployment %s: %w", d.ID, err)
+			}
+		}
+		deps[i] = deployment.ToGatewayDeployment(d)
+
+		if d.Protection != nil {
+			deps[i].Credentials, err = repo.GetCredentials(ctx, d.ID)
+			if err != nil {
+				return fmt.Errorf("failed to fetch credentials of deployment %s: %w", d.ID, err)
+			}
+		}
+	}
+
+	credentials, err := projectRepo.GetCredentials(ctx, project.GetSingleProject{ID: projectID})
+	if err != nil {
+		return fmt.Errorf("failed to fetch project credentials: %w", err)
+	}
+
+	gp := deployment.ToGatewayProject(*p, credentials)
+
+	return tg.WriteProjectConfig(gp, deps)
+}
+
+// readyDeployments lists every ready deployment of a project, reading all pages so the production and
+// canary deployments are included however many newer deployments there are.
+func readyDeployments(ctx context.Context, repo deployment.DeploymentRepository, projectID string) ([]deployment.Deployment, error) {
+	status := deployment.StatusReady
+	var deps []deployment.Deployment
+
+	for page := 1; ; page++ {
+		paged, err := repo.GetPaged(ctx, deployment.GetPagedDeployment{
+			PagingParams: request.PagingParams{PageNumber: page, PageSize: 100},
+			ProjectID:    &projectID,
+			Status:       &status,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ready deployments: %w", err)
+		}
+
+		deps = append(deps, paged.Items...)
+		if int64(page) >= paged.PageCount {
+			return deps, nil
+		}
+	}
+}
+
+// deploymentPath is the storage name of a deployment's files.
+func deploymentPath(d deployment.Deployment) string {
+	return "deployments/" + d.ProjectID + "/" + d.ID
+}
@@ -0,0 +1,63 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dimasbaguspm/infario/internal/gateway"
+)
+
+const (
+	probeAttempts = 3
+	probeTimeout  = 5 * time.Second
+	probeInterval = 2 * time.Second
+)
+
+// probeUpstreams checks every upstream answers its health path before a deployment goes live.
+func probeUpstreams(ctx context.Context, upstreams []gateway.Upstream) error {
+	for _, upstream := range upstreams {
+		if err := probeUpstream(ctx, upstream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// probeUpstream sends GET requests to an upstream's health URL until one gets a response below 500.
+// Client errors count as healthy: the backend is up, it just does not serve the path.
+func probeUpstream(ctx context.Context, upstream gateway.Upstream) error {
+	client := &http.Client{Timeout: probeTimeout}
+	target := upstream.HealthURL()
+
+	var lastErr error
+	for attempt := 1; attempt <= probeAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(probeInterval):
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return fmt.Errorf("upstream %s: invalid health URL: %w", upstream.Path, err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < http.StatusInternalServerError {
+			return nil
+		}
+		lastErr = fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return fmt.Errorf("upstream %s failed its health check at %s: %v", upstream.Path, target, lastErr)
+}
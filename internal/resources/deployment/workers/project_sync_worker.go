@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/internal/platform/engine"
+	"github.com/dimasbaguspm/infario/internal/resources/deployment"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// StartProjectSyncConsumer drains project sync tasks from Redis and regenerates the project's nginx config.
+// Tasks are cheap and idempotent, so they are processed one at a time.
+func StartProjectSyncConsumer(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	tg *gateway.NginxGateway,
+	redisClient *redis.Client,
+	fileEngine *engine.FileEngine,
+	logger *slog.Logger,
+) {
+	go func() {
+		repo := deployment.NewPostgresRepository(db)
+		projectRepo := project.NewPostgresRepository(db)
+
+		if logger != nil {
+			logger.InfoContext(ctx, "project sync consumer started")
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				if logger != nil {
+					logger.InfoContext(ctx, "project sync consumer shutting down")
+				}
+				return
+			default:
+			}
+
+			result, err := redisClient.BLPop(ctx, 1*time.Second, project.SyncQueueKey).Result()
+			if err != nil {
+				if err != redis.Nil && logger != nil {
+					logger.ErrorContext(ctx, "failed to pop from queue", "error", err)
+				}
+				continue
+			}
+
+			if len(result) < 2 {
+				continue
+			}
+
+			var task project.SyncTask
+			if err := json.Unmarshal([]byte(result[1]), &task); err != nil {
+				if logger != nil {
+					logger.ErrorContext(ctx, "failed to unmarshal task", "error", err)
+				}
+				continue
+			}
+
+			if tg == nil {
+				continue
+			}
+
+			if err := syncProjectConfig(ctx, repo, projectRepo, fileEngine, tg, task.ProjectID); err != nil && logger != nil {
+				logger.ErrorContext(ctx, "failed to sync project config", "project_id", task.ProjectID, "error", err)
+			}
+		}
+	}()
+}
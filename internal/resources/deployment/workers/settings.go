@@ -0,0 +1,104 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/internal/platform/engine"
+	"github.com/dimasbaguspm/infario/internal/resources/deployment"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
+)
+
+// resolveServingMode picks the serving mode chosen at upload, then infario.json, then the project
+// setting, and otherwise SPA for entry files and static for entry directories.
+func resolveServingMode(
+	ctx context.Context,
+	dep *deployment.Deployment,
+	manifest *deployment.Manifest,
+	entryPath string,
+	projectRepo project.ProjectRepository,
+) (string, error) {
+	if dep.ServingMode != nil && *dep.ServingMode != "" {
+		return *dep.ServingMode, nil
+	}
+	if manifest.ServingMode != "" {
+		return manifest.ServingMode, nil
+	}
+
+	p, err := projectRepo.GetByID(ctx, project.GetSingleProject{ID: dep.ProjectID})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch project: %w", err)
+	}
+	if p.ServingMode != nil && *p.ServingMode != "" {
+		return *p.ServingMode, nil
+	}
+
+	if strings.HasSuffix(entryPath, "/") {
+		return gateway.ServingModeStatic, nil
+	}
+	return gateway.ServingModeSPA, nil
+}
+
+// documentRoot returns the directory an entry path is served from.
+func documentRoot(entryPath string) string {
+	if strings.HasSuffix(entryPath, "/") {
+		return entryPath
+	}
+	return path.Dir(entryPath)
+}
+
+// readManifest parses the optional infario.json at the given storage path.
+// An empty manifest is returned when the archive has none.
+func readManifest(fileEngine *engine.FileEngine, name string) (*deployment.Manifest, error) {
+	file, err := fileEngine.FS().Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &deployment.Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", deployment.ManifestFile, err)
+	}
+	defer file.Close()
+
+	return deployment.ParseManifest(file)
+}
+
+// readRedirects parses the optional _redirects file at the given storage path.
+func readRedirects(fileEngine *engine.FileEngine, name string) ([]gateway.RedirectRule, error) {
+	file, err := fileEngine.FS().Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open _redirects: %w", err)
+	}
+	defer file.Close()
+
+	rules, err := gateway.ParseRedirects(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid _redirects: %w", err)
+	}
+	return rules, nil
+}
+
+// readHeaders parses the optional _headers file at the given storage path.
+func readHeaders(fileEngine *engine.FileEngine, name string) ([]gateway.HeaderRule, error) {
+	file, err := fileEngine.FS().Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open _headers: %w", err)
+	}
+	defer file.Close()
+
+	rules, err := gateway.ParseHeaders(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid _headers: %w", err)
+	}
+	return rules, nil
+}
@@ -0,0 +1,97 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dimasbaguspm/infario/internal/platform/engine"
+	"github.com/dimasbaguspm/infario/internal/platform/scheduler"
+	"github.com/dimasbaguspm/infario/internal/resources/deployment"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// UploadCleanupInterval defines how often to check for abandoned resumable uploads
+	UploadCleanupInterval = 15 * time.Minute
+	// UploadCleanupConcurrency limits concurrent cleanup workers
+	UploadCleanupConcurrency = 5
+)
+
+// StartUploadCleanup initializes and starts the abandoned upload cleanup worker.
+// This periodically removes the received bytes of resumable uploads that stopped receiving chunks
+// and marks their deployments as failed.
+func StartUploadCleanup(
+	ctx context.Context,
+	db *pgxpool.Pool,
+	fileEngine *engine.FileEngine,
+	logger *slog.Logger,
+) {
+	repo := deployment.NewPostgresRepository(db)
+
+	// Define retriever: expired upload records, plus staged uploads whose record is gone, e.g. with
+	// their project
+	retriever := func(ctx context.Context) ([]string, error) {
+		ids, err := repo.GetAbandonedUploads(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stale, err := fileEngine.StaleUploads(deployment.UploadSessionTTL)
+		if err != nil && logger != nil {
+			logger.Error("failed to list staged uploads", "err", err)
+		}
+
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			seen[id] = true
+		}
+		for _, id := range stale {
+			if !seen[id] {
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	// Define executor: remove the received bytes, then the record, and fail the deployment
+	executor := func(ctx context.Context, id string) error {
+		// Busy uploads are receiving a chunk after all and are retried on the next run
+		if err := fileEngine.RemoveUpload(ctx, id); err != nil {
+			return err
+		}
+
+		// Finalizing deletes the record too; only whoever deletes it proceeds
+		deleted, err := repo.DeleteUpload(ctx, id)
+		if err != nil || !deleted {
+			return err
+		}
+
+		return repo.UpdateStatus(ctx, deployment.UpdateDeploymentStatus{
+			ID:      id,
+			Status:  deployment.StatusError,
+			Message: "Upload was abandoned before it was finalized",
+		})
+	}
+
+	// Define error handler for executor failures
+	onError := func(id string, err error) {
+		if logger != nil {
+			logger.Error("upload cleanup failed", "id", id, "err", err)
+		}
+	}
+
+	// Create and start the maintenance runner
+	runner := scheduler.NewMaintenanceRunner(
+		UploadCleanupInterval,
+		UploadCleanupConcurrency,
+		retriever,
+		executor,
+		onError,
+		logger,
+	)
+
+	if logger != nil {
+		logger.InfoContext(ctx, "upload cleanup worker started", "interval", UploadCleanupInterval, "concurrency", UploadCleanupConcurrency)
+	}
+	go runner.Start(ctx)
+}
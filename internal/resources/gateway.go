@@ -0,0 +1,17 @@
+package resources
+
+import (
+	"log/slog"
+
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/internal/platform/engine"
+	"github.com/dimasbaguspm/infario/internal/resources/deployment"
+	"github.com/dimasbaguspm/infario/internal/resources/project"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InitStaticGateway builds the Go-native static gateway that serves deployments from FileEngine storage.
+func InitStaticGateway(db *pgxpool.Pool, fileEngine *engine.FileEngine, routing gateway.Routing) *gateway.StaticGateway {
+	resolver := deployment.NewGatewayResolver(deployment.NewPostgresRepository(db), project.NewPostgresRepository(db))
+	return gateway.NewStaticGateway(routing, resolver, fileEngine.FS(), slog.Default())
+}
@@ -3,6 +3,7 @@ package resources
 import (
 	"net/http"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
 	"github.com/dimasbaguspm/infario/internal/platform/engine"
 	"github.com/dimasbaguspm/infario/internal/resources/deployment"
 	"github.com/dimasbaguspm/infario/internal/resources/project"
@@ -10,7 +11,7 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-func InitHttps(mux *http.ServeMux, db *pgxpool.Pool, redisClient *redis.Client, fileEngine *engine.FileEngine) {
-	project.Init(mux, db)
-	deployment.InitHttp(mux, db, redisClient, fileEngine)
+func InitHttps(mux *http.ServeMux, db *pgxpool.Pool, redisClient *redis.Client, fileEngine *engine.FileEngine, routing gateway.Routing) {
+	project.Init(mux, db, redisClient, routing)
+	deployment.InitHttp(mux, db, redisClient, fileEngine, routing)
 }
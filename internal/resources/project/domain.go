@@ -4,19 +4,43 @@ import (
 	"context"
 	"time"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
 	"github.com/dimasbaguspm/infario/pkgs/request"
 	"github.com/dimasbaguspm/infario/pkgs/response"
 )
 
+// SyncQueueKey is the Redis queue consumed to regenerate a project's gateway config
+// after settings that affect routing change.
+const SyncQueueKey = "project_sync"
+
+// SyncTask asks the gateway worker to regenerate the config of a single project.
+type SyncTask struct {
+	ProjectID string `json:"project_id"`
+}
+
 // Project represents a project entity in the system.
 // @Description Project entity representing a project with its metadata
 // @Name Project
 type Project struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	ID                     string                 `json:"id"`
+	Name                   string                 `json:"name"`
+	ServingMode            *string                `json:"serving_mode,omitempty"`             // Default serving mode for new deployments
+	Redirects              []gateway.RedirectRule `json:"redirects"`                          // Applied after each deployment's _redirects rules
+	Headers                []gateway.HeaderRule   `json:"headers"`                            // Defaults overridden by each deployment's _headers rules
+	ProductionDeploymentID *string                `json:"production_deployment_id,omitempty"` // Deployment served on {project}.{domain}
+	Protection             *Protection            `json:"protection,omitempty"`               // Basic auth settings, absent when public
+	ProductionAccess       gateway.AccessRules    `json:"production_access"`                  // CIDR rules for the production hostname
+	PreviewAccess          gateway.AccessRules    `json:"preview_access"`                     // CIDR rules for preview hostnames
+	RateLimit              gateway.RateLimit      `json:"rate_limit"`                         // Zero values use the gateway defaults
+	Maintenance            *Maintenance           `json:"maintenance,omitempty"`              // Set while every hostname serves a 503 page
+	Canary                 *Canary                `json:"canary,omitempty"`                   // Set while production traffic is split
+	DeploymentRouting      bool                   `json:"deployment_routing"`                 // X-Infario-Deployment header and preview cookie routing on the production hostname
+	ArchiveFormats         []string               `json:"archive_formats"`                    // Upload formats accepted, every supported format when empty
+	URL                    string                 `json:"url,omitempty"`                      // Public URL of the project, set once a deployment is promoted
+	ProductionURL          string                 `json:"production_url,omitempty"`           // URL serving the production deployment
+	CreatedAt              time.Time              `json:"created_at"`
+	UpdatedAt              time.Time              `json:"updated_at"`
+	DeletedAt              *time.Time             `json:"deleted_at,omitempty"`
 }
 
 // GetSingleProject represents the payload for retrieving a project by ID.
@@ -42,15 +66,123 @@ type ProjectPaged response.Collection[*Project]
 // @Description Project creation DTO
 // @Name CreateProject
 type CreateProject struct {
-	Name string `json:"name" validate:"required,min=3,max=100"`
+	Name              string                 `json:"name" validate:"required,min=3,max=100"`
+	ServingMode       string                 `json:"serving_mode,omitempty" validate:"omitempty,oneof=spa static clean_urls"`
+	Redirects         []gateway.RedirectRule `json:"redirects,omitempty" validate:"omitempty,dive"`
+	Headers           []gateway.HeaderRule   `json:"headers,omitempty" validate:"omitempty,dive"`
+	ProductionAccess  *gateway.AccessRules   `json:"production_access,omitempty"`
+	PreviewAccess     *gateway.AccessRules   `json:"preview_access,omitempty"`
+	RateLimit         *gateway.RateLimit     `json:"rate_limit,omitempty"`
+	DeploymentRouting *bool                  `json:"deployment_routing,omitempty"` // Defaults to false
+	ArchiveFormats    []string               `json:"archive_formats,omitempty" validate:"omitempty,unique,dive,oneof=zip tar tar.gz tar.bz2 tar.zst tar.xz"`
 }
 
 // UpdateProject represents the payload for updating existing projects.
 // @Description Project update DTO
 // @Name UpdateProject
 type UpdateProject struct {
-	ID   string `json:"id" validate:"required,uuid4"`
-	Name string `json:"name,omitempty" validate:"omitempty,min=3,max=100"`
+	ID                string                  `json:"id" validate:"required,uuid4"`
+	Name              string                  `json:"name,omitempty" validate:"omitempty,min=3,max=100"`
+	ServingMode       string                  `json:"serving_mode,omitempty" validate:"omitempty,oneof=spa static clean_urls"`
+	Redirects         *[]gateway.RedirectRule `json:"redirects,omitempty" validate:"omitempty,dive"` // Replaces all rules when set
+	Headers           *[]gateway.HeaderRule   `json:"headers,omitempty" validate:"omitempty,dive"`   // Replaces all rules when set
+	ProductionAccess  *gateway.AccessRules    `json:"production_access,omitempty"`                   // Replaces both lists when set
+	PreviewAccess     *gateway.AccessRules    `json:"preview_access,omitempty"`                      // Replaces both lists when set
+	RateLimit         *gateway.RateLimit      `json:"rate_limit,omitempty"`                          // Replaces all limits when set
+	DeploymentRouting *bool                   `json:"deployment_routing,omitempty"`
+	ArchiveFormats    *[]string               `json:"archive_formats,omitempty" validate:"omitempty,unique,dive,oneof=zip tar tar.gz tar.bz2 tar.zst tar.xz"` // Replaces the list when set; empty allows every format
+}
+
+// Protection describes a project's basic auth settings without exposing password hashes.
+// @Description Basic auth protection of a project's hostnames
+// @Name Protection
+type Protection struct {
+	Scope     string   `json:"scope"` // "all", "previews" (production hostname stays public) or "production" (previews stay public)
+	Usernames []string `json:"usernames"`
+}
+
+// Credential is a basic auth user submitted in plain text and stored as a bcrypt hash.
+// @Description Basic auth user
+// @Name Credential
+type Credential struct {
+	Username string `json:"username" validate:"required,max=64,printascii,excludes=:"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// SetProtection represents the payload for protecting a project with basic auth.
+// @Description Basic auth protection DTO, replacing any existing users
+// @Name SetProtection
+type SetProtection struct {
+	ID          string       `json:"id" validate:"required,uuid4"`
+	Scope       string       `json:"scope" validate:"required,oneof=all previews production"`
+	Credentials []Credential `json:"credentials" validate:"required,min=1,max=20,unique=Username,dive"`
+}
+
+// RemoveProtection represents the payload for making a project public again.
+// @Description Basic auth removal DTO
+// @Name RemoveProtection
+type RemoveProtection struct {
+	ID string `json:"id" validate:"required,uuid4"`
+}
+
+// Maintenance describes an active maintenance mode.
+// @Description Maintenance mode taking every hostname of a project offline
+// @Name Maintenance
+type Maintenance struct {
+	EnabledAt  time.Time `json:"enabled_at"`
+	BypassIPs  []string  `json:"bypass_ips"` // Addresses or CIDR ranges still served normally
+	CustomPage bool      `json:"custom_page"`
+	Page       string    `json:"-"`
+}
+
+// EnableMaintenance represents the payload for putting a project into maintenance mode.
+// @Description Maintenance mode DTO
+// @Name EnableMaintenance
+type EnableMaintenance struct {
+	ID        string   `json:"id" validate:"required,uuid4"`
+	HTML      string   `json:"html,omitempty" validate:"omitempty,max=262144"` // Custom 503 page; a default page is used when empty
+	BypassIPs []string `json:"bypass_ips,omitempty" validate:"omitempty,max=100,dive,cidr|ip"`
+}
+
+// DisableMaintenance represents the payload for bringing a project back online.
+// @Description Maintenance mode removal DTO
+// @Name DisableMaintenance
+type DisableMaintenance struct {
+	ID string `json:"id" validate:"required,uuid4"`
+}
+
+// PromoteDeployment represents the payload for serving a deployment on the production hostname.
+// @Description Production promotion DTO
+// @Name PromoteDeployment
+type PromoteDeployment struct {
+	ID           string `json:"id" validate:"required,uuid4"`
+	DeploymentID string `json:"deployment_id" validate:"required,uuid4"`
+}
+
+// Canary describes a weighted split of production traffic with another deployment.
+// @Description Canary split of production traffic
+// @Name Canary
+type Canary struct {
+	DeploymentID string `json:"deployment_id"`
+	Weight       int    `json:"weight"` // Percentage of clients sent to the canary deployment
+	Key          string `json:"key"`    // "cookie" or "ip"
+}
+
+// SetCanary represents the payload for starting or adjusting a canary.
+// @Description Canary DTO
+// @Name SetCanary
+type SetCanary struct {
+	ID           string `json:"id" validate:"required,uuid4"`
+	DeploymentID string `json:"deployment_id" validate:"required,uuid4"`
+	Weight       int    `json:"weight" validate:"required,min=1,max=99"`
+	Key          string `json:"key,omitempty" validate:"omitempty,oneof=cookie ip"` // Defaults to cookie
+}
+
+// FinishCanary represents the payload for promoting or aborting a canary.
+// @Description Canary completion DTO
+// @Name FinishCanary
+type FinishCanary struct {
+	ID string `json:"id" validate:"required,uuid4"`
 }
 
 // DeleteProject represents the payload for deleting a project.
@@ -63,9 +195,19 @@ type DeleteProject struct {
 type ProjectRepository interface {
 	GetPaged(ctx context.Context, params GetPagedProject) (*ProjectPaged, error)
 	GetByID(ctx context.Context, p GetSingleProject) (*Project, error)
+	GetIDByName(ctx context.Context, name string) (string, error)
 	Create(ctx context.Context, p CreateProject) (string, error)
 	Update(ctx context.Context, p UpdateProject) error
 	Delete(ctx context.Context, p DeleteProject) error
+	GetCredentials(ctx context.Context, p GetSingleProject) ([]gateway.Credential, error)
+	SetProtection(ctx context.Context, p SetProtection, credentials []gateway.Credential) error
+	RemoveProtection(ctx context.Context, p RemoveProtection) error
+	Promote(ctx context.Context, p PromoteDeployment) error
+	EnableMaintenance(ctx context.Context, p EnableMaintenance) error
+	DisableMaintenance(ctx context.Context, p DisableMaintenance) error
+	SetCanary(ctx context.Context, p SetCanary) error
+	PromoteCanary(ctx context.Context, p FinishCanary) error
+	AbortCanary(ctx context.Context, p FinishCanary) error
 }
 
 type ProjectService interface {
@@ -74,4 +216,12 @@ type ProjectService interface {
 	CreateNewProject(ctx context.Context, p CreateProject) (*Project, error)
 	UpdateProject(ctx context.Context, p UpdateProject) (*Project, error)
 	DeleteProject(ctx context.Context, p DeleteProject) error
+	SetProtection(ctx context.Context, p SetProtection) (*Project, error)
+	RemoveProtection(ctx context.Context, p RemoveProtection) (*Project, error)
+	PromoteDeployment(ctx context.Context, p PromoteDeployment) (*Project, error)
+	EnableMaintenance(ctx context.Context, p EnableMaintenance) (*Project, error)
+	DisableMaintenance(ctx context.Context, p DisableMaintenance) (*Project, error)
+	SetCanary(ctx context.Context, p SetCanary) (*Project, error)
+	PromoteCanary(ctx context.Context, p FinishCanary) (*Project, error)
+	AbortCanary(ctx context.Context, p FinishCanary) (*Project, error)
 }
@@ -3,12 +3,14 @@ package project
 import (
 	"net/http"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
-func Init(mux *http.ServeMux, pgx *pgxpool.Pool) {
+func Init(mux *http.ServeMux, pgx *pgxpool.Pool, redisClient *redis.Client, routing gateway.Routing) {
 	repo := NewPostgresRepository(pgx)
-	service := NewService(repo)
+	service := NewService(repo, redisClient, routing)
 
 	RegisterRoutes(mux, *service)
 }
@@ -3,8 +3,11 @@ package project
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
 	"github.com/dimasbaguspm/infario/pkgs/response"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -24,6 +27,23 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedProjec
 			SELECT
 				id,
 				name,
+				serving_mode,
+				redirects,
+				headers,
+				production_deployment_id,
+				auth_scope,
+				production_access,
+				preview_access,
+				rate_limit,
+				maintenance_enabled_at,
+				maintenance_page,
+				maintenance_bypass,
+				canary_deployment_id,
+				canary_weight,
+				canary_key,
+				deployment_routing,
+				archive_formats,
+				(SELECT COALESCE(array_agg(c.username ORDER BY c.username), '{}') FROM project_credentials c WHERE c.project_id = projects.id) AS usernames,
 				created_at,
 				updated_at,
 				deleted_at,
@@ -36,6 +56,23 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedProjec
 		SELECT
 			id,
 			name,
+			serving_mode,
+			redirects,
+			headers,
+			production_deployment_id,
+			auth_scope,
+			production_access,
+			preview_access,
+			rate_limit,
+			maintenance_enabled_at,
+			maintenance_page,
+			maintenance_bypass,
+			canary_deployment_id,
+			canary_weight,
+			canary_key,
+			deployment_routing,
+			archive_formats,
+			usernames,
 			created_at,
 			updated_at,
 			deleted_at,
@@ -54,9 +91,30 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedProjec
 
 	for rows.Next() {
 		project := &Project{}
+		var authScope *string
+		var usernames []string
+		var maintenance maintenanceColumns
+		var canary canaryColumns
 		err := rows.Scan(
 			&project.ID,
 			&project.Name,
+			&project.ServingMode,
+			&project.Redirects,
+			&project.Headers,
+			&project.ProductionDeploymentID,
+			&authScope,
+			&project.ProductionAccess,
+			&project.PreviewAccess,
+			&project.RateLimit,
+			&maintenance.enabledAt,
+			&maintenance.page,
+			&maintenance.bypass,
+			&canary.deploymentID,
+			&canary.weight,
+			&canary.key,
+			&project.DeploymentRouting,
+			&project.ArchiveFormats,
+			&usernames,
 			&project.CreatedAt,
 			&project.UpdatedAt,
 			&project.DeletedAt,
@@ -65,6 +123,9 @@ func (r *PostgresRepository) GetPaged(ctx context.Context, params GetPagedProjec
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan project row: %w", err)
 		}
+		project.Protection = newProtection(authScope, usernames)
+		project.Maintenance = maintenance.toMaintenance()
+		project.Canary = canary.toCanary()
 		projects = append(projects, project)
 	}
 
@@ -89,6 +150,23 @@ func (r *PostgresRepository) GetByID(ctx context.Context, p GetSingleProject) (*
 		SELECT
 			id,
 			name,
+			serving_mode,
+			redirects,
+			headers,
+			production_deployment_id,
+			auth_scope,
+			production_access,
+			preview_access,
+			rate_limit,
+			maintenance_enabled_at,
+			maintenance_page,
+			maintenance_bypass,
+			canary_deployment_id,
+			canary_weight,
+			canary_key,
+			deployment_routing,
+			archive_formats,
+			(SELECT COALESCE(array_agg(c.username ORDER BY c.username), '{}') FROM project_credentials c WHERE c.project_id = projects.id) AS usernames,
 			created_at,
 			updated_at,
 			deleted_at
@@ -98,9 +176,30 @@ func (r *PostgresRepository) GetByID(ctx context.Context, p GetSingleProject) (*
 	`
 
 	d := &Project{}
+	var authScope *string
+	var usernames []string
+	var maintenance maintenanceColumns
+	var canary canaryColumns
 	err := r.db.QueryRow(ctx, query, p.ID).Scan(
 		&d.ID,
 		&d.Name,
+		&d.ServingMode,
+		&d.Redirects,
+		&d.Headers,
+		&d.ProductionDeploymentID,
+		&authScope,
+		&d.ProductionAccess,
+		&d.PreviewAccess,
+		&d.RateLimit,
+		&maintenance.enabledAt,
+		&maintenance.page,
+		&maintenance.bypass,
+		&canary.deploymentID,
+		&canary.weight,
+		&canary.key,
+		&d.DeploymentRouting,
+		&d.ArchiveFormats,
+		&usernames,
 		&d.CreatedAt,
 		&d.UpdatedAt,
 		&d.DeletedAt,
@@ -110,19 +209,56 @@ func (r *PostgresRepository) GetByID(ctx context.Context, p GetSingleProject) (*
 		return nil, err
 	}
 
+	d.Protection = newProtection(authScope, usernames)
+	d.Maintenance = maintenance.toMaintenance()
+	d.Canary = canary.toCanary()
 	return d, nil
 }
 
+// GetIDByName returns the ID of the live project with a name, compared case-insensitively as hostnames are.
+func (r *PostgresRepository) GetIDByName(ctx context.Context, name string) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, `
+		SELECT id
+		FROM projects
+		WHERE LOWER(name) = LOWER($1)
+			AND deleted_at IS NULL
+	`, name).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
 func (r *PostgresRepository) Create(ctx context.Context, p CreateProject) (string, error) {
 	var ID *string
 
 	query := `
-		INSERT INTO projects (name)
-		VALUES ($1)
+		INSERT INTO projects (name, serving_mode, redirects, headers, production_access, preview_access, rate_limit, deployment_routing, archive_formats)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, COALESCE($8, FALSE), $9)
 		RETURNING id
 	`
 
-	err := r.db.QueryRow(ctx, query, p.Name).Scan(&ID)
+	redirects := p.Redirects
+	if redirects == nil {
+		redirects = []gateway.RedirectRule{}
+	}
+	headers := p.Headers
+	if headers == nil {
+		headers = []gateway.HeaderRule{}
+	}
+
+	rateLimit := gateway.RateLimit{}
+	if p.RateLimit != nil {
+		rateLimit = *p.RateLimit
+	}
+	archiveFormats := p.ArchiveFormats
+	if archiveFormats == nil {
+		archiveFormats = []string{}
+	}
+
+	err := r.db.QueryRow(ctx, query, p.Name, p.ServingMode, redirects, headers,
+		accessRules(p.ProductionAccess), accessRules(p.PreviewAccess), rateLimit, p.DeploymentRouting, archiveFormats).Scan(&ID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create project: %w", err)
 	}
@@ -134,12 +270,30 @@ func (r *PostgresRepository) Update(ctx context.Context, p UpdateProject) error
 	query := `
 		UPDATE projects
 		SET name = COALESCE(NULLIF($1, ''), name),
+			serving_mode = COALESCE(NULLIF($2, ''), serving_mode),
+			redirects = COALESCE($3, redirects),
+			headers = COALESCE($4, headers),
+			production_access = COALESCE($5, production_access),
+			preview_access = COALESCE($6, preview_access),
+			rate_limit = COALESCE($7, rate_limit),
+			deployment_routing = COALESCE($8, deployment_routing),
+			archive_formats = COALESCE($9, archive_formats),
 			updated_at = NOW()
-		WHERE id = $2
+		WHERE id = $10
 			AND deleted_at IS NULL
 	`
 
-	_, err := r.db.Exec(ctx, query, p.Name, p.ID)
+	var productionAccess, previewAccess *gateway.AccessRules
+	if p.ProductionAccess != nil {
+		rules := accessRules(p.ProductionAccess)
+		productionAccess = &rules
+	}
+	if p.PreviewAccess != nil {
+		rules := accessRules(p.PreviewAccess)
+		previewAccess = &rules
+	}
+
+	_, err := r.db.Exec(ctx, query, p.Name, p.ServingMode, p.Redirects, p.Headers, productionAccess, previewAccess, p.RateLimit, p.DeploymentRouting, p.ArchiveFormats, p.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update project: %w", err)
 	}
@@ -161,3 +315,313 @@ func (r *PostgresRepository) Delete(ctx context.Context, p DeleteProject) error
 
 	return nil
 }
+
+// GetCredentials returns the basic auth users of a project, ordered by username.
+func (r *PostgresRepository) GetCredentials(ctx context.Context, p GetSingleProject) ([]gateway.Credential, error) {
+	query := `
+		SELECT username, password_hash
+		FROM project_credentials
+		WHERE project_id = $1
+		ORDER BY username
+	`
+
+	rows, err := r.db.Query(ctx, query, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []gateway.Credential
+	for rows.Next() {
+		var c gateway.Credential
+		if err := rows.Scan(&c.Username, &c.PasswordHash); err != nil {
+			return nil, fmt.Errorf("failed to scan credential row: %w", err)
+		}
+		credentials = append(credentials, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating credential rows: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// SetProtection replaces the basic auth users and scope of a project in a single transaction.
+func (r *PostgresRepository) SetProtection(ctx context.Context, p SetProtection, credentials []gateway.Credential) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE projects
+			SET auth_scope = $1,
+				updated_at = NOW()
+			WHERE id = $2
+				AND deleted_at IS NULL
+		`, p.Scope, p.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update auth scope: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM project_credentials WHERE project_id = $1`, p.ID); err != nil {
+			return fmt.Errorf("failed to clear credentials: %w", err)
+		}
+
+		for _, c := range credentials {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO project_credentials (project_id, username, password_hash)
+				VALUES ($1, $2, $3)
+			`, p.ID, c.Username, c.PasswordHash)
+			if err != nil {
+				return fmt.Errorf("failed to insert credential: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveProtection deletes the basic auth users of a project, making every hostname public.
+func (r *PostgresRepository) RemoveProtection(ctx context.Context, p RemoveProtection) error {
+	return pgx.BeginFunc(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM project_credentials WHERE project_id = $1`, p.ID); err != nil {
+			return fmt.Errorf("failed to clear credentials: %w", err)
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE projects
+			SET auth_scope = NULL,
+				updated_at = NOW()
+			WHERE id = $1
+		`, p.ID)
+		if err != nil {
+			return fmt.Errorf("failed to clear auth scope: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Promote serves a ready deployment of the project on its production hostname, ending any canary.
+// Returns pgx.ErrNoRows when the deployment is not a ready deployment of the project.
+func (r *PostgresRepository) Promote(ctx context.Context, p PromoteDeployment) error {
+	query := `
+		UPDATE projects
+		SET production_deployment_id = $1,
+			canary_deployment_id = NULL,
+			canary_weight = NULL,
+			canary_key = NULL,
+			updated_at = NOW()
+		WHERE id = $2
+			AND deleted_at IS NULL
+			AND EXISTS (
+				SELECT 1 FROM deployments d
+				WHERE d.id = $1
+					AND d.project_id = projects.id
+					AND d.status = 'ready'
+			)
+	`
+
+	tag, err := r.db.Exec(ctx, query, p.DeploymentID, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to promote deployment: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// EnableMaintenance turns maintenance mode on, replacing the page and bypass list of an active one.
+func (r *PostgresRepository) EnableMaintenance(ctx context.Context, p EnableMaintenance) error {
+	query := `
+		UPDATE projects
+		SET maintenance_enabled_at = COALESCE(maintenance_enabled_at, NOW()),
+			maintenance_page = NULLIF($1, ''),
+			maintenance_bypass = $2,
+			updated_at = NOW()
+		WHERE id = $3
+			AND deleted_at IS NULL
+	`
+
+	bypass := p.BypassIPs
+	if bypass == nil {
+		bypass = []string{}
+	}
+
+	tag, err := r.db.Exec(ctx, query, p.HTML, bypass, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to enable maintenance: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// DisableMaintenance turns maintenance mode off and forgets its page and bypass list.
+func (r *PostgresRepository) DisableMaintenance(ctx context.Context, p DisableMaintenance) error {
+	query := `
+		UPDATE projects
+		SET maintenance_enabled_at = NULL,
+			maintenance_page = NULL,
+			maintenance_bypass = '[]',
+			updated_at = NOW()
+		WHERE id = $1
+			AND deleted_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to disable maintenance: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetCanary starts or adjusts a canary split between the production deployment and another ready
+// deployment of the project. Returns pgx.ErrNoRows when the project has no production deployment or
+// the canary is not another ready deployment of the project.
+func (r *PostgresRepository) SetCanary(ctx context.Context, p SetCanary) error {
+	query := `
+		UPDATE projects
+		SET canary_deployment_id = $1,
+			canary_weight = $2,
+			canary_key = $3,
+			updated_at = NOW()
+		WHERE id = $4
+			AND deleted_at IS NULL
+			AND production_deployment_id IS NOT NULL
+			AND production_deployment_id <> $1
+			AND EXISTS (
+				SELECT 1 FROM deployments d
+				WHERE d.id = $1
+					AND d.project_id = projects.id
+					AND d.status = 'ready'
+			)
+	`
+
+	tag, err := r.db.Exec(ctx, query, p.DeploymentID, p.Weight, p.Key, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to set canary: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// PromoteCanary makes the canary deployment the production deployment.
+// Returns pgx.ErrNoRows when no canary is running.
+func (r *PostgresRepository) PromoteCanary(ctx context.Context, p FinishCanary) error {
+	query := `
+		UPDATE projects
+		SET production_deployment_id = canary_deployment_id,
+			canary_deployment_id = NULL,
+			canary_weight = NULL,
+			canary_key = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+			AND deleted_at IS NULL
+			AND canary_deployment_id IS NOT NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to promote canary: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// AbortCanary sends all production traffic back to the production deployment.
+// Returns pgx.ErrNoRows when no canary is running.
+func (r *PostgresRepository) AbortCanary(ctx context.Context, p FinishCanary) error {
+	query := `
+		UPDATE projects
+		SET canary_deployment_id = NULL,
+			canary_weight = NULL,
+			canary_key = NULL,
+			updated_at = NOW()
+		WHERE id = $1
+			AND deleted_at IS NULL
+			AND canary_deployment_id IS NOT NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to abort canary: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// canaryColumns holds the canary columns of a project row.
+type canaryColumns struct {
+	deploymentID *string
+	weight       *int
+	key          *string
+}
+
+// toCanary builds the canary of a project, or nil when none is running.
+func (c canaryColumns) toCanary() *Canary {
+	if c.deploymentID == nil || c.weight == nil || c.key == nil {
+		return nil
+	}
+	return &Canary{DeploymentID: *c.deploymentID, Weight: *c.weight, Key: *c.key}
+}
+
+// maintenanceColumns holds the maintenance columns of a project row.
+type maintenanceColumns struct {
+	enabledAt *time.Time
+	page      *string
+	bypass    []string
+}
+
+// toMaintenance builds the maintenance mode of a project, or nil when it is not enabled.
+func (m maintenanceColumns) toMaintenance() *Maintenance {
+	if m.enabledAt == nil {
+		return nil
+	}
+	maintenance := &Maintenance{EnabledAt: *m.enabledAt, BypassIPs: m.bypass}
+	if m.page != nil {
+		maintenance.CustomPage = true
+		maintenance.Page = *m.page
+	}
+	return maintenance
+}
+
+// newProtection builds the protection settings of a project, or nil when it has no users.
+func newProtection(scope *string, usernames []string) *Protection {
+	if scope == nil || len(usernames) == 0 {
+		return nil
+	}
+	return &Protection{Scope: *scope, Usernames: usernames}
+}
+
+// accessRules returns the rules to store, with empty lists instead of nulls.
+func accessRules(rules *gateway.AccessRules) gateway.AccessRules {
+	stored := gateway.AccessRules{Allow: []string{}, Deny: []string{}}
+	if rules != nil {
+		if rules.Allow != nil {
+			stored.Allow = rules.Allow
+		}
+		if rules.Deny != nil {
+			stored.Deny = rules.Deny
+		}
+	}
+	return stored
+}
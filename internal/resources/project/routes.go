@@ -1,11 +1,16 @@
 package project
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/dimasbaguspm/infario/pkgs/request"
 	"github.com/dimasbaguspm/infario/pkgs/response"
+	"github.com/jackc/pgx/v5"
 )
 
 type handler struct {
@@ -20,6 +25,14 @@ func RegisterRoutes(mux *http.ServeMux, s Service) {
 	mux.HandleFunc("POST /projects", h.handleCreateProject)
 	mux.HandleFunc("PATCH /projects/{id}", h.handleUpdateProject)
 	mux.HandleFunc("DELETE /projects/{id}", h.handleDeleteProject)
+	mux.HandleFunc("PUT /projects/{id}/protection", h.handleSetProtection)
+	mux.HandleFunc("DELETE /projects/{id}/protection", h.handleRemoveProtection)
+	mux.HandleFunc("POST /projects/{id}/promote", h.handlePromoteDeployment)
+	mux.HandleFunc("POST /projects/{id}/maintenance", h.handleEnableMaintenance)
+	mux.HandleFunc("DELETE /projects/{id}/maintenance", h.handleDisableMaintenance)
+	mux.HandleFunc("PUT /projects/{id}/canary", h.handleSetCanary)
+	mux.HandleFunc("POST /projects/{id}/canary/promote", h.handlePromoteCanary)
+	mux.HandleFunc("POST /projects/{id}/canary/abort", h.handleAbortCanary)
 }
 
 // handleGetPagedProjects lists projects with offset-based pagination.
@@ -167,3 +180,311 @@ func (h *handler) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
 
 	response.JSON(w, http.StatusNoContent, nil)
 }
+
+// handleSetProtection protects a project's hostnames with basic auth.
+// @Summary      Protect a project with basic auth
+// @Description  Replaces the project's basic auth users. Scope "previews" leaves the production hostname public and "production" leaves preview hostnames public. Deployments with users of their own keep them on their preview hostname.
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Param request body SetProtection true "Scope and users"
+// @Success      200 {object} Project
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      404 {object} response.ErrorResponse "Project not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/protection [put]
+func (h *handler) handleSetProtection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req SetProtection
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.ID = id
+
+	project, err := h.service.SetProtection(r.Context(), req)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, project)
+}
+
+// handleRemoveProtection removes basic auth from a project's hostnames.
+// @Summary      Remove basic auth from a project
+// @Tags         projects
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Success      200 {object} Project
+// @Failure      404 {object} response.ErrorResponse "Project not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/protection [delete]
+func (h *handler) handleRemoveProtection(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	project, err := h.service.RemoveProtection(r.Context(), RemoveProtection{ID: id})
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, project)
+}
+
+// handlePromoteDeployment serves a ready deployment on the project's production hostname.
+// @Summary      Promote a deployment to production
+// @Description  Serves the deployment on {project}.{domain} in addition to its preview hostname.
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Param request body PromoteDeployment true "Deployment to promote"
+// @Success      200 {object} Project
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      404 {object} response.ErrorResponse "Ready deployment not found in project"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/promote [post]
+func (h *handler) handlePromoteDeployment(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req PromoteDeployment
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.ID = id
+
+	project, err := h.service.PromoteDeployment(r.Context(), req)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Ready deployment not found in project")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, project)
+}
+
+// handleEnableMaintenance takes every hostname of a project offline with a 503 page.
+// @Summary      Enable maintenance mode
+// @Description  Accepts JSON, or a multipart form with an uploaded page file and repeated bypass_ips fields.
+// @Tags         projects
+// @Accept       json,mpfd
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Param request body EnableMaintenance false "Custom page and bypass list"
+// @Param page formData file false "Custom HTML page (multipart only)"
+// @Success      200 {object} Project
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      404 {object} response.ErrorResponse "Project not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/maintenance [post]
+func (h *handler) handleEnableMaintenance(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	req, err := parseEnableMaintenance(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.ID = id
+
+	project, err := h.service.EnableMaintenance(r.Context(), *req)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, project)
+}
+
+// handleDisableMaintenance brings a project back online.
+// @Summary      Disable maintenance mode
+// @Tags         projects
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Success      200 {object} Project
+// @Failure      404 {object} response.ErrorResponse "Project not found"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/maintenance [delete]
+func (h *handler) handleDisableMaintenance(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	project, err := h.service.DisableMaintenance(r.Context(), DisableMaintenance{ID: id})
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, project)
+}
+
+// handleSetCanary starts or adjusts a canary split on the production hostname.
+// @Summary      Start or adjust a canary
+// @Description  Sends the given percentage of production clients to another ready deployment. Requires a production deployment.
+// @Tags         projects
+// @Accept       json
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Param request body SetCanary true "Canary deployment and weight"
+// @Success      200 {object} Project
+// @Failure      400 {object} response.ErrorResponse "Invalid request body"
+// @Failure      404 {object} response.ErrorResponse "Project has no production deployment or canary deployment is not ready"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/canary [put]
+func (h *handler) handleSetCanary(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var req SetCanary
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.ID = id
+
+	project, err := h.service.SetCanary(r.Context(), req)
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "Project has no production deployment or canary deployment is not ready")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, project)
+}
+
+// handlePromoteCanary makes the canary deployment the production deployment.
+// @Summary      Promote a canary
+// @Tags         projects
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Success      200 {object} Project
+// @Failure      404 {object} response.ErrorResponse "No canary running"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/canary/promote [post]
+func (h *handler) handlePromoteCanary(w http.ResponseWriter, r *http.Request) {
+	h.finishCanary(w, r, h.service.PromoteCanary)
+}
+
+// handleAbortCanary sends all production traffic back to the production deployment.
+// @Summary      Abort a canary
+// @Tags         projects
+// @Produce      json
+// @Param id path string true "Project ID"
+// @Success      200 {object} Project
+// @Failure      404 {object} response.ErrorResponse "No canary running"
+// @Failure      422 {object} response.ErrorResponse "Validation failed"
+// @Failure      500 {object} response.ErrorResponse "Internal Server Error"
+// @Router       /projects/{id}/canary/abort [post]
+func (h *handler) handleAbortCanary(w http.ResponseWriter, r *http.Request) {
+	h.finishCanary(w, r, h.service.AbortCanary)
+}
+
+// finishCanary runs a canary promotion or abort and writes the updated project.
+func (h *handler) finishCanary(w http.ResponseWriter, r *http.Request, finish func(context.Context, FinishCanary) (*Project, error)) {
+	id := r.PathValue("id")
+
+	project, err := finish(r.Context(), FinishCanary{ID: id})
+	if err != nil {
+		if fields := response.MapValidationErrors(err); len(fields) > 0 {
+			response.Error(w, http.StatusUnprocessableEntity, "Validation failed", fields)
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Error(w, http.StatusNotFound, "No canary running")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, project)
+}
+
+// parseEnableMaintenance reads the maintenance payload from a multipart form or a JSON body.
+// An empty body enables maintenance with the default page.
+func parseEnableMaintenance(r *http.Request) (*EnableMaintenance, error) {
+	req := &EnableMaintenance{}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			return nil, err
+		}
+		req.BypassIPs = r.MultipartForm.Value["bypass_ips"]
+
+		file, _, err := r.FormFile("page")
+		if errors.Is(err, http.ErrMissingFile) {
+			return req, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		page, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		req.HTML = string(page)
+		return req, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return req, nil
+}
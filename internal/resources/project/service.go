@@ -4,15 +4,20 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/pkgs/redis"
 	"github.com/dimasbaguspm/infario/pkgs/validator"
+	goredis "github.com/redis/go-redis/v9"
 )
 
 type Service struct {
-	repo ProjectRepository
+	repo    ProjectRepository
+	redis   *goredis.Client
+	routing gateway.Routing
 }
 
-func NewService(repo ProjectRepository) *Service {
-	return &Service{repo: repo}
+func NewService(repo ProjectRepository, redisClient *goredis.Client, routing gateway.Routing) *Service {
+	return &Service{repo: repo, redis: redisClient, routing: routing}
 }
 
 func (s *Service) GetPagedProjects(ctx context.Context, params GetPagedProject) (*ProjectPaged, error) {
@@ -23,6 +28,9 @@ func (s *Service) GetPagedProjects(ctx context.Context, params GetPagedProject)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to list projects: %w", err)
 	}
+	for _, p := range page.Items {
+		s.setURL(p)
+	}
 	return page, nil
 }
 
@@ -34,9 +42,18 @@ func (s *Service) GetProjectByID(ctx context.Context, p GetSingleProject) (*Proj
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get project by id: %w", err)
 	}
+	s.setURL(resp)
 	return resp, nil
 }
 
+// setURL fills in the public URLs of a project that has a production deployment.
+func (s *Service) setURL(p *Project) {
+	if p.ProductionDeploymentID != nil {
+		p.ProductionURL = s.routing.ProductionURL(p.Name)
+		p.URL = p.ProductionURL
+	}
+}
+
 func (s *Service) CreateNewProject(ctx context.Context, p CreateProject) (*Project, error) {
 	if err := validator.Validate.Struct(p); err != nil {
 		return nil, fmt.Errorf("Validation failed: %w", err)
@@ -56,6 +73,9 @@ func (s *Service) UpdateProject(ctx context.Context, p UpdateProject) (*Project,
 	if err != nil {
 		return nil, fmt.Errorf("Failed to update project: %w", err)
 	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
 	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
 }
 
@@ -67,5 +87,130 @@ func (s *Service) DeleteProject(ctx context.Context, p DeleteProject) error {
 	if err != nil {
 		return fmt.Errorf("Failed to delete project: %w", err)
 	}
+	return s.requestSync(ctx, p.ID)
+}
+
+// requestSync queues a gateway config regeneration for the project.
+func (s *Service) requestSync(ctx context.Context, projectID string) error {
+	if err := redis.Emit(ctx, s.redis, SyncQueueKey, SyncTask{ProjectID: projectID}); err != nil {
+		return fmt.Errorf("Failed to queue gateway sync: %w", err)
+	}
 	return nil
 }
+
+func (s *Service) SetProtection(ctx context.Context, p SetProtection) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+
+	credentials := make([]gateway.Credential, len(p.Credentials))
+	for i, c := range p.Credentials {
+		hash, err := gateway.HashPassword(c.Password)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to hash password: %w", err)
+		}
+		credentials[i] = gateway.Credential{Username: c.Username, PasswordHash: hash}
+	}
+
+	if err := s.repo.SetProtection(ctx, p, credentials); err != nil {
+		return nil, fmt.Errorf("Failed to set project protection: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
+
+func (s *Service) RemoveProtection(ctx context.Context, p RemoveProtection) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if err := s.repo.RemoveProtection(ctx, p); err != nil {
+		return nil, fmt.Errorf("Failed to remove project protection: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
+
+func (s *Service) PromoteDeployment(ctx context.Context, p PromoteDeployment) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if err := s.repo.Promote(ctx, p); err != nil {
+		return nil, fmt.Errorf("Failed to promote deployment: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
+
+func (s *Service) EnableMaintenance(ctx context.Context, p EnableMaintenance) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if err := s.repo.EnableMaintenance(ctx, p); err != nil {
+		return nil, fmt.Errorf("Failed to enable maintenance: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
+
+func (s *Service) DisableMaintenance(ctx context.Context, p DisableMaintenance) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if err := s.repo.DisableMaintenance(ctx, p); err != nil {
+		return nil, fmt.Errorf("Failed to disable maintenance: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
+
+func (s *Service) SetCanary(ctx context.Context, p SetCanary) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if p.Key == "" {
+		p.Key = gateway.CanaryKeyCookie
+	}
+	if err := s.repo.SetCanary(ctx, p); err != nil {
+		return nil, fmt.Errorf("Failed to set canary: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
+
+func (s *Service) PromoteCanary(ctx context.Context, p FinishCanary) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if err := s.repo.PromoteCanary(ctx, p); err != nil {
+		return nil, fmt.Errorf("Failed to promote canary: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
+
+func (s *Service) AbortCanary(ctx context.Context, p FinishCanary) (*Project, error) {
+	if err := validator.Validate.Struct(p); err != nil {
+		return nil, fmt.Errorf("Validation failed: %w", err)
+	}
+	if err := s.repo.AbortCanary(ctx, p); err != nil {
+		return nil, fmt.Errorf("Failed to abort canary: %w", err)
+	}
+	if err := s.requestSync(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return s.GetProjectByID(ctx, GetSingleProject{ID: p.ID})
+}
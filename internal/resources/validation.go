@@ -0,0 +1,14 @@
+package resources
+
+import (
+	"github.com/dimasbaguspm/infario/internal/gateway"
+	"github.com/dimasbaguspm/infario/internal/resources/deployment"
+	"github.com/dimasbaguspm/infario/pkgs/validator"
+)
+
+// InitValidations registers the struct-level checks of every resource with the shared validator.
+// It must run before any request or task is validated.
+func InitValidations() {
+	gateway.RegisterValidations(validator.Validate)
+	deployment.RegisterValidations(validator.Validate)
+}
@@ -23,4 +23,7 @@ func InitWorkers(
 
 	workers.StartDeploymentConsumer(ctx, db, ng, redisClient, fileEngine, logger)
 	workers.StartExpiryCleanup(ctx, db, fileEngine, ng, logger)
+	workers.StartUploadCleanup(ctx, db, fileEngine, logger)
+	workers.StartDeltaCleanup(ctx, db, fileEngine, logger)
+	workers.StartProjectSyncConsumer(ctx, db, ng, redisClient, fileEngine, logger)
 }
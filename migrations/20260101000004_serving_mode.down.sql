@@ -0,0 +1,2 @@
+ALTER TABLE deployments DROP COLUMN serving_mode;
+ALTER TABLE projects DROP COLUMN serving_mode;
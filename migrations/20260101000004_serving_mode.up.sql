@@ -0,0 +1,2 @@
+ALTER TABLE projects ADD COLUMN serving_mode VARCHAR(20);
+ALTER TABLE deployments ADD COLUMN serving_mode VARCHAR(20);
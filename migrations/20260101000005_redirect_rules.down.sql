@@ -0,0 +1,3 @@
+ALTER TABLE deployments DROP COLUMN error_message;
+ALTER TABLE deployments DROP COLUMN redirects;
+ALTER TABLE projects DROP COLUMN redirects;
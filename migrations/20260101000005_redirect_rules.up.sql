@@ -0,0 +1,3 @@
+ALTER TABLE projects ADD COLUMN redirects JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE deployments ADD COLUMN redirects JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE deployments ADD COLUMN error_message TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE deployments DROP COLUMN headers;
+ALTER TABLE projects DROP COLUMN headers;
@@ -0,0 +1,2 @@
+ALTER TABLE projects ADD COLUMN headers JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE deployments ADD COLUMN headers JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1 @@
+ALTER TABLE deployments ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE projects ADD COLUMN production_deployment_id UUID REFERENCES deployments (id) ON DELETE SET NULL;
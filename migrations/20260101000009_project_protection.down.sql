@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS project_credentials;
+ALTER TABLE projects DROP COLUMN auth_scope;
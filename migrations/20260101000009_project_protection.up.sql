@@ -0,0 +1,12 @@
+ALTER TABLE projects ADD COLUMN auth_scope VARCHAR(20);
+
+CREATE TABLE IF NOT EXISTS project_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+    username VARCHAR(64) NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (project_id, username)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_credentials_project_id ON project_credentials (project_id);
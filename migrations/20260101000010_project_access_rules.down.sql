@@ -0,0 +1,2 @@
+ALTER TABLE projects DROP COLUMN preview_access;
+ALTER TABLE projects DROP COLUMN production_access;
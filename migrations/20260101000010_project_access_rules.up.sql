@@ -0,0 +1,2 @@
+ALTER TABLE projects ADD COLUMN production_access JSONB NOT NULL DEFAULT '{"allow": [], "deny": []}';
+ALTER TABLE projects ADD COLUMN preview_access JSONB NOT NULL DEFAULT '{"allow": [], "deny": []}';
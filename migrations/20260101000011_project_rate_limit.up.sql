@@ -0,0 +1 @@
+ALTER TABLE projects ADD COLUMN rate_limit JSONB NOT NULL DEFAULT '{}';
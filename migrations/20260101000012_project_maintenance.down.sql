@@ -0,0 +1,3 @@
+ALTER TABLE projects DROP COLUMN maintenance_bypass;
+ALTER TABLE projects DROP COLUMN maintenance_page;
+ALTER TABLE projects DROP COLUMN maintenance_enabled_at;
@@ -0,0 +1,3 @@
+ALTER TABLE projects ADD COLUMN maintenance_enabled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE projects ADD COLUMN maintenance_page TEXT;
+ALTER TABLE projects ADD COLUMN maintenance_bypass JSONB NOT NULL DEFAULT '[]';
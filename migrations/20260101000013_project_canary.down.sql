@@ -0,0 +1,3 @@
+ALTER TABLE projects DROP COLUMN canary_key;
+ALTER TABLE projects DROP COLUMN canary_weight;
+ALTER TABLE projects DROP COLUMN canary_deployment_id;
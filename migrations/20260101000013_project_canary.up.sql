@@ -0,0 +1,3 @@
+ALTER TABLE projects ADD COLUMN canary_deployment_id UUID REFERENCES deployments (id) ON DELETE SET NULL;
+ALTER TABLE projects ADD COLUMN canary_weight INTEGER;
+ALTER TABLE projects ADD COLUMN canary_key VARCHAR(20);
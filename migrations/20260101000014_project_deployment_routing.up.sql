@@ -0,0 +1 @@
+ALTER TABLE projects ADD COLUMN deployment_routing BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE deployments DROP COLUMN upstreams;
+ALTER TABLE deployments DROP COLUMN type;
@@ -0,0 +1,2 @@
+ALTER TABLE deployments ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'static';
+ALTER TABLE deployments ADD COLUMN upstreams JSONB NOT NULL DEFAULT '[]';
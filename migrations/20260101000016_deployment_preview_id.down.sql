@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_deployments_project_preview_id;
+ALTER TABLE deployments DROP COLUMN preview_id;
@@ -0,0 +1,19 @@
+ALTER TABLE deployments ADD COLUMN preview_id VARCHAR(63);
+
+-- Hashes that already are DNS labels keep their hostnames
+UPDATE deployments
+SET preview_id = LOWER(hash)
+WHERE hash ~* '^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$';
+
+-- Everything else, and hashes differing only in case within a project, get an ID derived from the deployment ID
+UPDATE deployments d
+SET preview_id = LEFT(md5(d.id::text), 12)
+WHERE d.preview_id IS NULL
+    OR EXISTS (
+        SELECT 1 FROM deployments o
+        WHERE o.project_id = d.project_id AND o.preview_id = d.preview_id AND o.id < d.id
+    );
+
+ALTER TABLE deployments ALTER COLUMN preview_id SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deployments_project_preview_id ON deployments (project_id, preview_id);
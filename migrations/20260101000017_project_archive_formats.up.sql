@@ -0,0 +1 @@
+ALTER TABLE projects ADD COLUMN archive_formats TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS deployment_files;
+ALTER TABLE deployments DROP COLUMN file_count;
+ALTER TABLE deployments DROP COLUMN total_size;
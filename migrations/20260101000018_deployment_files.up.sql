@@ -0,0 +1,12 @@
+ALTER TABLE deployments ADD COLUMN total_size BIGINT;
+ALTER TABLE deployments ADD COLUMN file_count INTEGER;
+
+CREATE TABLE IF NOT EXISTS deployment_files (
+    deployment_id UUID NOT NULL REFERENCES deployments (id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    mode INTEGER NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    PRIMARY KEY (deployment_id, path)
+);
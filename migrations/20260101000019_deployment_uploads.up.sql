@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS deployment_uploads (
+    deployment_id UUID PRIMARY KEY REFERENCES deployments (id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployment_uploads_expires_at ON deployment_uploads (expires_at);
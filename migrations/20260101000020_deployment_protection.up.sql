@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS deployment_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deployment_id UUID NOT NULL REFERENCES deployments (id) ON DELETE CASCADE,
+    username VARCHAR(64) NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (deployment_id, username)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployment_credentials_deployment_id ON deployment_credentials (deployment_id);
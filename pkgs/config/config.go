@@ -17,7 +17,41 @@ type Config struct {
 
 	RedisURL string `env:"REDIS_URL,required"`
 
-	NginxDomain string `env:"NGINX_DOMAIN" envDefault:"infario.site"`
+	NginxDomain   string `env:"NGINX_DOMAIN" envDefault:"infario.site"`
+	NginxResolver string `env:"NGINX_RESOLVER" envDefault:"127.0.0.11"`
+
+	// GatewayRouting serves deployments on subdomains ("subdomain") or under paths of NGINX_DOMAIN ("path").
+	GatewayRouting string `env:"GATEWAY_ROUTING" envDefault:"subdomain"`
+	// GatewayScheme is the scheme of public URLs in API responses; set "https" when TLS terminates in front of nginx.
+	GatewayScheme string `env:"GATEWAY_SCHEME" envDefault:"http"`
+
+	// Default per-client limits for projects without their own rate limit settings.
+	NginxRateLimit int `env:"NGINX_RATE_LIMIT" envDefault:"20"` // Requests per second
+	NginxRateBurst int `env:"NGINX_RATE_BURST" envDefault:"40"`
+	NginxConnLimit int `env:"NGINX_CONN_LIMIT" envDefault:"50"` // Concurrent connections
+
+	// StorageDriver selects where deployment files live: "local" (./storage) or "s3".
+	StorageDriver string `env:"STORAGE_DRIVER" envDefault:"local"`
+	S3Endpoint    string `env:"S3_ENDPOINT"`
+	S3Region      string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket      string `env:"S3_BUCKET"`
+	S3AccessKey   string `env:"S3_ACCESS_KEY"`
+	S3SecretKey   string `env:"S3_SECRET_KEY"`
+	S3PathStyle   bool   `env:"S3_PATH_STYLE" envDefault:"true"` // Required by MinIO and most self-hosted stores
+
+	// Limits on uploaded archives, enforced while extracting; 0 disables a limit.
+	ExtractMaxArchiveBytes int64   `env:"EXTRACT_MAX_ARCHIVE_BYTES" envDefault:"1073741824"` // Compressed bytes of the archive
+	ExtractMaxBytes        int64   `env:"EXTRACT_MAX_BYTES" envDefault:"1073741824"`         // Uncompressed bytes in total
+	ExtractMaxFileBytes    int64   `env:"EXTRACT_MAX_FILE_BYTES" envDefault:"268435456"`     // Uncompressed bytes per file
+	ExtractMaxFiles        int     `env:"EXTRACT_MAX_FILES" envDefault:"10000"`
+	ExtractMaxDepth        int     `env:"EXTRACT_MAX_DEPTH" envDefault:"32"`
+	ExtractMaxRatio        float64 `env:"EXTRACT_MAX_RATIO" envDefault:"100"` // Uncompressed bytes per compressed byte
+
+	// StagingMaxAge is how old leftover staging directories must be before the startup sweep removes them.
+	StagingMaxAge time.Duration `env:"STAGING_MAX_AGE" envDefault:"1h"`
+
+	// StaticGatewayAddr enables the Go-native static gateway on this address when set (e.g. ":8081").
+	StaticGatewayAddr string `env:"STATIC_GATEWAY_ADDR"`
 }
 
 func Load() *Config {
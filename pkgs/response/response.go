@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -51,6 +52,24 @@ func MapValidationErrors(err error) map[string]string {
 	return nil
 }
 
+// MapValidationErrorPaths is like MapValidationErrors but keys each error by its full field path
+// below the validated struct (e.g. "redirects[0].from"), which keeps nested errors apart.
+func MapValidationErrorPaths(err error) map[string]string {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		out := make(map[string]string, len(ve))
+		for _, fe := range ve {
+			field := fe.Namespace()
+			if _, rest, found := strings.Cut(field, "."); found {
+				field = rest
+			}
+			out[field] = msgForTag(fe.Tag(), fe.Param())
+		}
+		return out
+	}
+	return nil
+}
+
 func msgForTag(tag string, param string) string {
 	switch tag {
 	case "uuid4":
@@ -67,6 +86,22 @@ func msgForTag(tag string, param string) string {
 		return fmt.Sprintf("Must be one of: %s", param)
 	case "alphanumhyphen":
 		return "Only alphanumeric characters and hyphens are allowed"
+	case "rule":
+		return param
+	case "len":
+		return fmt.Sprintf("Must be exactly %s characters", param)
+	case "hexadecimal":
+		return "Must be a hexadecimal string"
+	case "lowercase":
+		return "Must be lowercase"
+	case "unique":
+		return "Must not contain duplicates"
+	case "startswith":
+		return fmt.Sprintf("Must start with %s", param)
+	case "cidr":
+		return "Must be a valid CIDR range, e.g. 10.0.0.0/8"
+	case "cidr|ip":
+		return "Must be a valid IP address or CIDR range"
 	}
 	return fmt.Sprintf("Field failed on tag: %s", tag)
 }